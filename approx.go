@@ -0,0 +1,151 @@
+// Copyright 2020 Hu Jun. All rights reserved.
+// This project is licensed under the terms of the MIT license.
+// license that can be found in the LICENSE file.
+
+package cmprule
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// approximate compare operators
+const (
+	opNumApprox        = "~="
+	opNumNotApprox     = "!~="
+	opNumApproxWord    = "approx"
+	opNumNotApproxWord = "notapprox"
+)
+
+// isApproxOp returns true if op is an approximate compare operator
+func isApproxOp(op string) bool {
+	switch op {
+	case opNumApprox, opNumNotApprox, opNumApproxWord, opNumNotApproxWord:
+		return true
+	}
+	return false
+}
+
+// approxValue is a parsed value of an approximate compare rule
+type approxValue struct {
+	expected  float64
+	tolerance float64
+	// relative is true if tolerance is a fraction of expected, instead of an absolute difference
+	relative bool
+}
+
+// match returns true if input is within tolerance of expected;
+// NaN only matches NaN, and an infinity only matches the infinity of same sign
+func (a approxValue) match(input float64) bool {
+	switch {
+	case math.IsNaN(a.expected) || math.IsNaN(input):
+		return math.IsNaN(a.expected) && math.IsNaN(input)
+	case math.IsInf(a.expected, 0) || math.IsInf(input, 0):
+		return input == a.expected
+	}
+	tolerance := a.tolerance
+	if a.relative {
+		tolerance *= math.Abs(a.expected)
+	}
+	return math.Abs(input-a.expected) <= tolerance
+}
+
+//format: "val ±tolerance", "val +-tolerance" or "val tolerance%", the space before ± is optional
+func defaultParseApproxFunc(input string) (string, string, error) {
+	fieldlist := strings.Fields(strings.Replace(input, "±", " ±", 1))
+	if len(fieldlist) != 2 {
+		return "", "", fmt.Errorf("invalid approximate value %v, expect a value and a tolerance like 12.5 ±0.01 or 12.5 1%%", input)
+	}
+	return fieldlist[0], fieldlist[1], nil
+}
+
+// parseApprox parses the value and tolerance strings of an approximate compare rule,
+// an absolute tolerance is like "±0.01", "+-0.01" or "0.01", a relative tolerance is like "1%" or "±1%"
+func (cmprule *CMPRule) parseApprox() error {
+	var a approxValue
+	var err error
+	a.expected, err = cmprule.parseNumFloat64Func(cmprule.approxValStr)
+	if err != nil {
+		return cmprule.valueError(cmprule.approxValStr, fmt.Sprintf("can't parse %v into float64", cmprule.approxValStr), err)
+	}
+	tol := cmprule.approxTolStr
+	for _, prefix := range []string{"±", "+-"} {
+		tol = strings.TrimPrefix(tol, prefix)
+	}
+	if strings.HasSuffix(tol, "%") {
+		a.relative = true
+		tol = strings.TrimSuffix(tol, "%")
+	}
+	a.tolerance, err = cmprule.parseNumFloat64Func(tol)
+	if err == nil && (a.tolerance < 0 || math.IsNaN(a.tolerance) || math.IsInf(a.tolerance, 0)) {
+		err = fmt.Errorf("tolerance must be a non-negative finite number")
+	}
+	if err != nil {
+		return cmprule.valueError(cmprule.approxTolStr, fmt.Sprintf("invalid tolerance %v", cmprule.approxTolStr), err)
+	}
+	if a.relative {
+		a.tolerance /= 100
+	}
+	cmprule.approx = a
+	return nil
+}
+
+// compareApprox compares a numberic element against the approximate value,
+// integers are converted to float64, a float32 is widened to float64
+func (cmprule *CMPRule) compareApprox(element interface{}) (bool, error) {
+	etype := reflect.TypeOf(element)
+	fieldVal := reflect.ValueOf(element)
+	var input float64
+	switch etype.String() {
+	case "int", "int8", "int16", "int32", "int64":
+		input = float64(fieldVal.Int())
+	case "uint", "uint8", "uint16", "uint32", "uint64":
+		input = float64(fieldVal.Uint())
+	case "float32", "float64":
+		input = fieldVal.Float()
+	case "json.Number":
+		f, err := element.(json.Number).Float64()
+		if err != nil {
+			return false, fmt.Errorf("field %v has invalid json number %v", cmprule.ruleFieldName, element)
+		}
+		input = f
+	case "string", "bool", "time.Duration", "time.Time", "net.IP", "net.IPNet", "netip.Addr", "netip.Prefix":
+		return false, cmprule.opError(etype.String())
+	default:
+		return false, cmprule.typeError(etype)
+	}
+	matched := cmprule.approx.match(input)
+	if cmprule.ruleOp == opNumNotApprox || cmprule.ruleOp == opNumNotApproxWord {
+		return !matched, nil
+	}
+	return matched, nil
+}
+
+// checkApproxType returns an error if approximate compare operators are not valid for type t
+func (cmprule *CMPRule) checkApproxType(t reflect.Type) error {
+	switch t.String() {
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64",
+		"float32", "float64", "json.Number":
+		return nil
+	case "string", "bool", "time.Duration", "time.Time", "net.IP", "net.IPNet", "netip.Addr", "netip.Prefix":
+		return cmprule.opError(t.String())
+	default:
+		return cmprule.typeError(t)
+	}
+}
+
+// floatEqual returns true if a equals b, or both are NaN
+func floatEqual(a, b float64) bool {
+	return a == b || (math.IsNaN(a) && math.IsNaN(b))
+}
+
+// SetParseApproxFunc set f as function to parse a string that represents an approximate value into two strings
+// contain the value and the tolerance.
+// this is used by all numberic types with operator ~=, !~=, approx and notapprox.
+// default function uses spaces as sperator, the space before ± is optional
+func (cmprule *CMPRule) SetParseApproxFunc(f func(approxval string) (string, string, error)) {
+	cmprule.parseApproxFunc = f
+}
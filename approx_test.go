@@ -0,0 +1,121 @@
+// cmprule_test
+package cmprule
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+type testStructApprox struct {
+	Rate   float64
+	Rate32 float32
+	Pkts   uint64
+	Delta  int
+	NaN    float64
+	Inf    float64
+	Num    json.Number
+	Str    string
+	Ratio  *float64
+	Rates  []float64
+}
+
+var test_list_approx = []testResult{
+	{"Rate : ~= : 12.5 ±0.01", true, false},
+	{"Rate : ~= : 12.5±0.001", false, false},
+	{"Rate : ~= : 12.5 +-0.005", true, false},
+	{"Rate : ~= : 12.5 0.001", false, false},
+	{"Rate : !~= : 12.5 ±0.001", true, false},
+	{"Rate : approx : 12.5 1%", true, false},
+	{"Rate : approx : 12.5 ±0.01%", false, false},
+	{"Rate : notapprox : 12.5 0.01%", true, false},
+	{"Rate32 : == : 0.1", false, false},
+	{"Rate32 : ~= : 0.1 0.0001%", true, false},
+	{"Pkts : approx : 1e6 0%", true, false},
+	{"Pkts : approx : 995000 1%", true, false},
+	{"Pkts : approx : 980000 1%", false, false},
+	{"Delta : ~= : -2 ±1", true, false},
+	{"Num : ~= : 100 ±0.5", true, false},
+	{"Ratio : ~= : 0.3333 ±0.001", true, false},
+	{"Rates[*] : ~= : 10 ±0.1", true, false},
+	{"Rates[*] : ~= : 10 ±0.05", false, false},
+	{"NaN : ~= : NaN 0", true, false},
+	{"NaN : ~= : 0 ±1e300", false, false},
+	{"NaN : !~= : 1 1%", true, false},
+	{"NaN : == : NaN", true, false},
+	{"NaN : != : NaN", false, false},
+	{"NaN : is : 1 NaN", true, false},
+	{"Rate : != : NaN", true, false},
+	{"Inf : ~= : +Inf 0", true, false},
+	{"Inf : ~= : -Inf 0", false, false},
+	{"Inf : ~= : 1e308 100%", false, false},
+	{"Rate : ~= : Inf 1%", false, false},
+	{"Str : ~= : 12.5 ±0.01", false, true},
+}
+
+func TestApprox(t *testing.T) {
+	ratio := 0.333
+	input := testStructApprox{
+		Rate: 12.504, Rate32: 0.1, Pkts: 1000000, Delta: -3, NaN: math.NaN(), Inf: math.Inf(1),
+		Num: "99.5", Str: "12.5", Ratio: &ratio, Rates: []float64{9.9, 10.1},
+	}
+	tableTest(input, test_list_approx, t)
+}
+
+func TestApproxParse(t *testing.T) {
+	invalid := []string{
+		"Rate : ~= : 12.5",
+		"Rate : ~= : 12.5 ±0.01 1%",
+		"Rate : ~= : abc ±0.01",
+		"Rate : ~= : 12.5 ±abc",
+		"Rate : approx : 12.5 -1%",
+		"Rate : approx : 12.5 NaN",
+		"Rate : approx : 12.5 Inf",
+	}
+	for _, r := range invalid {
+		rule := NewDefaultCMPRule()
+		err := rule.ParseRule(r)
+		if err == nil {
+			t.Fatalf("rule %v expect to fail but succeed", r)
+		}
+		var perr *ParseError
+		if !errors.As(err, &perr) {
+			t.Fatalf("rule %v returns %T, expect *ParseError", r, err)
+		}
+		t.Logf("rule %v returns expected error %v", r, err)
+	}
+	rule := NewDefaultCMPRule()
+	if err := rule.ParseRule("Rate : ~= : 12.5 ±0.01"); err != nil {
+		t.Fatal(err)
+	}
+	if e := rule.Explain(testStructApprox{Rate: 12.6}); e != "Rate = 12.6, expected ~= 12.5 ±0.01" {
+		t.Fatalf("unexpected explanation %v", e)
+	}
+	def := rule.RuleDef()
+	if def.String() != "Rate : ~= : 12.5 ±0.01" {
+		t.Fatalf("unexpected rule def %v", def)
+	}
+	defs, err := ParseRuleDefsJSON([]byte(`[{"field": "Rate", "op": "approx", "value": [12.5, "1%"]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rule.ParseRuleDef(defs[0]); err != nil {
+		t.Fatal(err)
+	}
+	if r, err := rule.Compare(testStructApprox{Rate: 12.6}); err != nil || !r {
+		t.Fatalf("rule def %v returns %v, %v", defs[0], r, err)
+	}
+}
+
+func TestApproxValidate(t *testing.T) {
+	validateTest(reflect.TypeOf(testStructApprox{}), []string{
+		"Rate : ~= : 12.5 ±0.01",
+		"Pkts : approx : 100 1%",
+		"Num : approx : 100 1%",
+		"Ratio : approx : 0.3 1%",
+	}, []string{
+		"Str : approx : 100 1%",
+	}, t)
+}
@@ -0,0 +1,739 @@
+// Copyright 2020 Hu Jun. All rights reserved.
+// This project is licensed under the terms of the MIT license.
+// license that can be found in the LICENSE file.
+
+package cmprule
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// arithmetic operators
+const (
+	arithAdd = "+"
+	arithSub = "-"
+	arithMul = "*"
+	arithDiv = "/"
+	arithMod = "%"
+)
+
+// arithmetic functions
+const (
+	arithAbs = "abs"
+	arithMin = "min"
+	arithMax = "max"
+)
+
+var interfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
+
+// arithNode is a node of an arithmetic expression;
+// eval returns the value of the node in input, visit is called with every field resolved;
+// typeOf returns the type of the node in root type t, it returns an interface type if the type is only known at eval time
+type arithNode interface {
+	eval(fr fieldResolver, input interface{}, visit func(FieldValue)) (interface{}, error)
+	typeOf(fr fieldResolver, t reflect.Type) (reflect.Type, error)
+}
+
+type arithLiteral struct {
+	value interface{}
+}
+
+func (n *arithLiteral) eval(fr fieldResolver, input interface{}, visit func(FieldValue)) (interface{}, error) {
+	return n.value, nil
+}
+
+func (n *arithLiteral) typeOf(fr fieldResolver, t reflect.Type) (reflect.Type, error) {
+	return reflect.TypeOf(n.value), nil
+}
+
+// arithField is a field operand, its value is the field value as it is, so a single field keeps its type
+type arithField struct {
+	name string
+	path []pathSegment
+}
+
+func (n *arithField) eval(fr fieldResolver, input interface{}, visit func(FieldValue)) (interface{}, error) {
+	var r interface{}
+	_, err := fr.walkField(input, "", n.path, func(name string, v interface{}) (bool, error) {
+		visit(FieldValue{Path: name, Value: v})
+		r = v
+		return true, nil
+	})
+	return r, err
+}
+
+func (n *arithField) typeOf(fr fieldResolver, t reflect.Type) (reflect.Type, error) {
+	return fr.resolveType(t, n.path)
+}
+
+type arithNeg struct {
+	raw     string
+	operand arithNode
+}
+
+func (n *arithNeg) eval(fr fieldResolver, input interface{}, visit func(FieldValue)) (interface{}, error) {
+	v, err := n.operand.eval(fr, input, visit)
+	if err != nil {
+		return nil, err
+	}
+	return arithNegate(n.raw, v)
+}
+
+func (n *arithNeg) typeOf(fr fieldResolver, t reflect.Type) (reflect.Type, error) {
+	return arithResultType(fr, t, []arithNode{n.operand}, func(v []interface{}) (interface{}, error) {
+		return arithNegate(n.raw, v[0])
+	})
+}
+
+type arithBinary struct {
+	raw         string
+	op          string
+	left, right arithNode
+}
+
+func (n *arithBinary) eval(fr fieldResolver, input interface{}, visit func(FieldValue)) (interface{}, error) {
+	a, err := n.left.eval(fr, input, visit)
+	if err != nil {
+		return nil, err
+	}
+	b, err := n.right.eval(fr, input, visit)
+	if err != nil {
+		return nil, err
+	}
+	return arithBinaryOp(n.raw, n.op, a, b)
+}
+
+func (n *arithBinary) typeOf(fr fieldResolver, t reflect.Type) (reflect.Type, error) {
+	return arithResultType(fr, t, []arithNode{n.left, n.right}, func(v []interface{}) (interface{}, error) {
+		return arithBinaryOp(n.raw, n.op, v[0], v[1])
+	})
+}
+
+type arithCall struct {
+	raw  string
+	fn   string
+	args []arithNode
+}
+
+func (n *arithCall) eval(fr fieldResolver, input interface{}, visit func(FieldValue)) (interface{}, error) {
+	var args []interface{}
+	for _, arg := range n.args {
+		v, err := arg.eval(fr, input, visit)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+	return arithCallFunc(n.raw, n.fn, args)
+}
+
+func (n *arithCall) typeOf(fr fieldResolver, t reflect.Type) (reflect.Type, error) {
+	return arithResultType(fr, t, n.args, func(v []interface{}) (interface{}, error) {
+		return arithCallFunc(n.raw, n.fn, v)
+	})
+}
+
+// arithResultType returns the result type of f applied to nodes, by applying f to a sample value of each node type
+func arithResultType(fr fieldResolver, t reflect.Type, nodes []arithNode, f func([]interface{}) (interface{}, error)) (reflect.Type, error) {
+	var samples []interface{}
+	for _, node := range nodes {
+		nt, err := node.typeOf(fr, t)
+		if err != nil {
+			return nil, err
+		}
+		if nt.Kind() == reflect.Interface {
+			return interfaceType, nil
+		}
+		s, err := arithSample(nt)
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, s)
+	}
+	r, err := f(samples)
+	if err != nil {
+		return nil, err
+	}
+	return reflect.TypeOf(r), nil
+}
+
+// arithSample returns a non-zero sample value of the arithmetic type of t
+func arithSample(t reflect.Type) (interface{}, error) {
+	switch t.String() {
+	case "time.Duration":
+		return time.Duration(1), nil
+	case "time.Time":
+		return time.Time{}, nil
+	case "json.Number", "float32", "float64":
+		return float64(1), nil
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64":
+		return int64(1), nil
+	}
+	return nil, arithTypeError(t.String(), t)
+}
+
+// arithTypeError returns a TypeMismatchError for token of type t can't be used in arithmetic
+func arithTypeError(token string, t reflect.Type) error {
+	return &TypeMismatchError{Token: token, Column: -1, Type: t.String(),
+		Msg: fmt.Sprintf("%v of type %v can't be used in arithmetic", token, t)}
+}
+
+// arithValue converts v into int64, float64, time.Duration or time.Time for arithmetic;
+// integers are converted into int64, or float64 if it overflows int64
+func arithValue(token string, v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case time.Duration, time.Time, int64, float64:
+		return v, nil
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return i, nil
+		}
+		f, err := val.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("field %v has invalid json number %v", token, val)
+		}
+		return f, nil
+	}
+	t := reflect.TypeOf(v)
+	switch t.String() {
+	case "int", "int8", "int16", "int32", "int64":
+		return reflect.ValueOf(v).Int(), nil
+	case "uint", "uint8", "uint16", "uint32", "uint64":
+		u := reflect.ValueOf(v).Uint()
+		if u > math.MaxInt64 {
+			return float64(u), nil
+		}
+		return int64(u), nil
+	case "float32", "float64":
+		return reflect.ValueOf(v).Float(), nil
+	}
+	return nil, arithTypeError(token, t)
+}
+
+// arithFloat returns a number a as float64
+func arithFloat(a interface{}) float64 {
+	if i, ok := a.(int64); ok {
+		return float64(i)
+	}
+	return a.(float64)
+}
+
+func isArithNumber(a interface{}) bool {
+	switch a.(type) {
+	case int64, float64:
+		return true
+	}
+	return false
+}
+
+func arithNegate(raw string, v interface{}) (interface{}, error) {
+	v, err := arithValue(raw, v)
+	if err != nil {
+		return nil, err
+	}
+	switch val := v.(type) {
+	case int64:
+		if val == math.MinInt64 {
+			return -float64(val), nil
+		}
+		return -val, nil
+	case float64:
+		return -val, nil
+	case time.Duration:
+		return -val, nil
+	}
+	return nil, arithTypeError(raw, reflect.TypeOf(v))
+}
+
+// arithBinaryOp returns a op b:
+//
+//	- integers result in int64, or float64 if it overflows; any float results in float64; / always results in float64
+//	- duration +,-,% duration results in duration, duration / duration results in float64
+//	- duration *,/ number and number * duration results in duration
+//	- time - time results in duration, time +,- duration and duration + time results in time
+func arithBinaryOp(raw, op string, a, b interface{}) (interface{}, error) {
+	a, err := arithValue(raw, a)
+	if err != nil {
+		return nil, err
+	}
+	b, err = arithValue(raw, b)
+	if err != nil {
+		return nil, err
+	}
+	mismatch := &TypeMismatchError{Token: raw, Column: -1, Type: reflect.TypeOf(a).String(),
+		Msg: fmt.Sprintf("can't apply %v to %v and %v in %v", op, reflect.TypeOf(a), reflect.TypeOf(b), raw)}
+	divByZero := &ParseError{Token: raw, Column: -1, Msg: fmt.Sprintf("division by zero in %v", raw)}
+	if isArithNumber(a) && isArithNumber(b) {
+		x, xok := a.(int64)
+		y, yok := b.(int64)
+		if xok && yok {
+			switch op {
+			case arithAdd:
+				if r := x + y; (r > x) == (y > 0) {
+					return r, nil
+				}
+			case arithSub:
+				if r := x - y; (r < x) == (y > 0) {
+					return r, nil
+				}
+			case arithMul:
+				if x == 0 || y == 0 {
+					return int64(0), nil
+				}
+				if r := x * y; r/y == x && !(x == -1 && y == math.MinInt64) && !(y == -1 && x == math.MinInt64) {
+					return r, nil
+				}
+			case arithMod:
+				if y == 0 {
+					return nil, divByZero
+				}
+				return x % y, nil
+			}
+		}
+		fx, fy := arithFloat(a), arithFloat(b)
+		switch op {
+		case arithAdd:
+			return fx + fy, nil
+		case arithSub:
+			return fx - fy, nil
+		case arithMul:
+			return fx * fy, nil
+		case arithDiv:
+			if fy == 0 {
+				return nil, divByZero
+			}
+			return fx / fy, nil
+		case arithMod:
+			if fy == 0 {
+				return nil, divByZero
+			}
+			return math.Mod(fx, fy), nil
+		}
+		return nil, mismatch
+	}
+	switch x := a.(type) {
+	case time.Duration:
+		switch y := b.(type) {
+		case time.Duration:
+			switch op {
+			case arithAdd:
+				return x + y, nil
+			case arithSub:
+				return x - y, nil
+			case arithDiv:
+				if y == 0 {
+					return nil, divByZero
+				}
+				return float64(x) / float64(y), nil
+			case arithMod:
+				if y == 0 {
+					return nil, divByZero
+				}
+				return x % y, nil
+			}
+		case time.Time:
+			if op == arithAdd {
+				return y.Add(x), nil
+			}
+		case int64:
+			switch op {
+			case arithMul:
+				return x * time.Duration(y), nil
+			case arithDiv:
+				if y == 0 {
+					return nil, divByZero
+				}
+				return x / time.Duration(y), nil
+			}
+		case float64:
+			switch op {
+			case arithMul:
+				return time.Duration(float64(x) * y), nil
+			case arithDiv:
+				if y == 0 {
+					return nil, divByZero
+				}
+				return time.Duration(float64(x) / y), nil
+			}
+		}
+	case time.Time:
+		switch y := b.(type) {
+		case time.Time:
+			if op == arithSub {
+				return x.Sub(y), nil
+			}
+		case time.Duration:
+			switch op {
+			case arithAdd:
+				return x.Add(y), nil
+			case arithSub:
+				return x.Add(-y), nil
+			}
+		}
+	case int64, float64:
+		if y, ok := b.(time.Duration); ok && op == arithMul {
+			return arithBinaryOp(raw, op, y, x)
+		}
+	}
+	return nil, mismatch
+}
+
+// arithCallFunc returns the result of function fn applied to args;
+// abs accepts a number or a duration, min and max accept numbers, durations or times
+func arithCallFunc(raw, fn string, args []interface{}) (interface{}, error) {
+	var values []interface{}
+	for _, arg := range args {
+		v, err := arithValue(raw, arg)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	if fn == arithAbs {
+		switch v := values[0].(type) {
+		case int64:
+			if v < 0 {
+				return arithNegate(raw, v)
+			}
+			return v, nil
+		case float64:
+			return math.Abs(v), nil
+		case time.Duration:
+			if v < 0 {
+				return -v, nil
+			}
+			return v, nil
+		}
+		return nil, arithTypeError(raw, reflect.TypeOf(values[0]))
+	}
+	r := values[0]
+	for _, v := range values[1:] {
+		if isArithNumber(r) && isArithNumber(v) {
+			x, xok := r.(int64)
+			y, yok := v.(int64)
+			switch {
+			case xok && yok && (fn == arithMin) == (y < x):
+				r = y
+			case !xok || !yok:
+				if fn == arithMin {
+					r = math.Min(arithFloat(r), arithFloat(v))
+				} else {
+					r = math.Max(arithFloat(r), arithFloat(v))
+				}
+			}
+			continue
+		}
+		if reflect.TypeOf(r) != reflect.TypeOf(v) || valueClass(reflect.TypeOf(r)) == classNumber {
+			return nil, &TypeMismatchError{Token: raw, Column: -1, Type: reflect.TypeOf(v).String(),
+				Msg: fmt.Sprintf("can't apply %v to %v and %v in %v", fn, reflect.TypeOf(r), reflect.TypeOf(v), raw)}
+		}
+		if order := orderValues(v, r); (fn == arithMin && order < 0) || (fn == arithMax && order > 0) {
+			r = v
+		}
+	}
+	return r, nil
+}
+
+// isArithExpr returns true if s is an arithmetic expression rather than a field name or a value:
+// it contains an operator separated by spaces, like "RxPkts - TxPkts", or starts with a function like "abs(Drift)" or '(';
+// spaces within brackets or double-quoted strings are ignored, so a field name like "Sessions[a - b]" is not an expression
+func isArithExpr(s string) bool {
+	s = strings.TrimSpace(s)
+	for _, fn := range []string{arithAbs, arithMin, arithMax} {
+		if strings.HasPrefix(s, fn+"(") {
+			return true
+		}
+	}
+	if strings.HasPrefix(s, "(") {
+		return true
+	}
+	for _, token := range splitArithTokens(s) {
+		switch token {
+		case arithAdd, arithSub, arithMul, arithDiv, arithMod:
+			return true
+		}
+	}
+	return false
+}
+
+// splitArithTokens splits s by spaces, spaces within brackets or double-quoted strings are not seperators
+func splitArithTokens(s string) []string {
+	var r []string
+	depth := 0
+	inQuote := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case inQuote:
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				inQuote = false
+			}
+		case c == '"':
+			inQuote = true
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		case depth == 0 && isExprSpace(c):
+			if start < i {
+				r = append(r, s[start:i])
+			}
+			start = i + 1
+		}
+	}
+	if start < len(s) {
+		r = append(r, s[start:])
+	}
+	return r
+}
+
+// arithParser is a recursive descent parser of an arithmetic expression:
+//
+//	expr    = term {("+" | "-") term}
+//	term    = unary {("*" | "/" | "%") unary}
+//	unary   = "-" unary | primary
+//	primary = "(" expr ")" | function "(" expr {"," expr} ")" | number | duration | ["$"] field_name
+//
+// binary operators must be separated by spaces, so that a field name could contain them, like "peer-1"
+type arithParser struct {
+	input     string
+	pos       int
+	parseName func(field_name string) []string
+}
+
+// parseArith parses input as an arithmetic expression, field names are parsed by parseName
+func parseArith(input string, parseName func(field_name string) []string) (arithNode, error) {
+	p := &arithParser{input: input, parseName: parseName}
+	node, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.input) {
+		return nil, p.error(p.input[p.pos:], fmt.Sprintf("unexpected %q in %v", p.input[p.pos:], input))
+	}
+	return node, nil
+}
+
+func (p *arithParser) error(token, msg string) error {
+	return &ParseError{Token: token, Column: -1, Msg: msg}
+}
+
+func (p *arithParser) skipSpace() {
+	for p.pos < len(p.input) && isExprSpace(p.input[p.pos]) {
+		p.pos++
+	}
+}
+
+// acceptOp reads a binary operator in ops, which must be followed by a space
+func (p *arithParser) acceptOp(ops string) (string, bool) {
+	p.skipSpace()
+	if p.pos+1 < len(p.input) && strings.IndexByte(ops, p.input[p.pos]) >= 0 && isExprSpace(p.input[p.pos+1]) {
+		op := p.input[p.pos : p.pos+1]
+		p.pos++
+		return op, true
+	}
+	return "", false
+}
+
+func (p *arithParser) parseExpr() (arithNode, error) {
+	start := p.pos
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.acceptOp(arithAdd + arithSub)
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		left = &arithBinary{raw: strings.TrimSpace(p.input[start:p.pos]), op: op, left: left, right: right}
+	}
+}
+
+func (p *arithParser) parseTerm() (arithNode, error) {
+	p.skipSpace()
+	start := p.pos
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.acceptOp(arithMul + arithDiv + arithMod)
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &arithBinary{raw: strings.TrimSpace(p.input[start:p.pos]), op: op, left: left, right: right}
+	}
+}
+
+func (p *arithParser) parseUnary() (arithNode, error) {
+	p.skipSpace()
+	start := p.pos
+	if p.pos < len(p.input) && p.input[p.pos] == '-' {
+		p.pos++
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &arithNeg{raw: p.input[start:p.pos], operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *arithParser) parsePrimary() (arithNode, error) {
+	p.skipSpace()
+	if p.pos >= len(p.input) {
+		return nil, p.error("", fmt.Sprintf("unexpected end of expression %v", p.input))
+	}
+	start := p.pos
+	if p.input[p.pos] == '(' {
+		p.pos++
+		node, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(')', start); err != nil {
+			return nil, err
+		}
+		return node, nil
+	}
+	token := p.readOperand()
+	if token == "" {
+		return nil, p.error(p.input[p.pos:], fmt.Sprintf("missing operand at %q in %v", p.input[p.pos:], p.input))
+	}
+	if p.pos < len(p.input) && p.input[p.pos] == '(' {
+		return p.parseCall(token, start)
+	}
+	if c := token[0]; c == '.' || (c >= '0' && c <= '9') {
+		return parseArithLiteral(token)
+	}
+	return p.parseField(token)
+}
+
+// readOperand reads a number or a field name, which ends at a space, ',', '(' or ')' out of brackets and double-quoted strings
+func (p *arithParser) readOperand() string {
+	start := p.pos
+	depth := 0
+	inQuote := false
+loop:
+	for ; p.pos < len(p.input); p.pos++ {
+		c := p.input[p.pos]
+		switch {
+		case inQuote:
+			if c == '\\' {
+				p.pos++
+			} else if c == '"' {
+				inQuote = false
+			}
+		case c == '"':
+			inQuote = true
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		case depth == 0 && (isExprSpace(c) || c == ',' || c == '(' || c == ')'):
+			break loop
+		}
+	}
+	if p.pos > len(p.input) {
+		p.pos = len(p.input)
+	}
+	return p.input[start:p.pos]
+}
+
+// expect reads the closing character c of the parenthesis at column start
+func (p *arithParser) expect(c byte, start int) error {
+	p.skipSpace()
+	if p.pos >= len(p.input) || p.input[p.pos] != c {
+		return p.error(p.input[start:], fmt.Sprintf("unclosed parenthesis in %v", p.input))
+	}
+	p.pos++
+	return nil
+}
+
+func (p *arithParser) parseCall(fn string, start int) (arithNode, error) {
+	switch fn {
+	case arithAbs, arithMin, arithMax:
+	default:
+		return nil, p.error(fn, fmt.Sprintf("unknown function %v in %v", fn, p.input))
+	}
+	p.pos++
+	var args []arithNode
+	for {
+		arg, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		p.skipSpace()
+		if p.pos < len(p.input) && p.input[p.pos] == ',' {
+			p.pos++
+			continue
+		}
+		if err := p.expect(')', start); err != nil {
+			return nil, err
+		}
+		break
+	}
+	raw := p.input[start:p.pos]
+	switch {
+	case fn == arithAbs && len(args) != 1:
+		return nil, p.error(raw, fmt.Sprintf("%v expects 1 argument, got %d", fn, len(args)))
+	case fn != arithAbs && len(args) < 2:
+		return nil, p.error(raw, fmt.Sprintf("%v expects at least 2 arguments, got %d", fn, len(args)))
+	}
+	return &arithCall{raw: raw, fn: fn, args: args}, nil
+}
+
+// parseArithLiteral parses token as an integer, a float or a duration
+func parseArithLiteral(token string) (arithNode, error) {
+	if i, err := strconv.ParseInt(token, 0, 64); err == nil {
+		return &arithLiteral{value: i}, nil
+	}
+	if f, err := strconv.ParseFloat(token, 64); err == nil {
+		return &arithLiteral{value: f}, nil
+	}
+	if d, err := time.ParseDuration(token); err == nil {
+		return &arithLiteral{value: d}, nil
+	}
+	return nil, &ParseError{Token: token, Column: -1, Msg: fmt.Sprintf("invalid number %v", token)}
+}
+
+// parseField parses token as a field name, optionally prefixed by "$";
+// the field must be a single field, so wildcard is not allowed
+func (p *arithParser) parseField(token string) (arithNode, error) {
+	name := strings.TrimPrefix(token, fieldRefPrefix)
+	if name == "" {
+		return nil, p.error(token, "missing field name after "+fieldRefPrefix)
+	}
+	path, err := parseFieldPath(p.parseName(name))
+	if err != nil {
+		return nil, err
+	}
+	for _, seg := range path {
+		for _, sel := range seg.selectors {
+			if sel.wildcard {
+				return nil, p.error(token, fmt.Sprintf("field %v must not contain wildcard", token))
+			}
+		}
+	}
+	return &arithField{name: name, path: path}, nil
+}
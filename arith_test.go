@@ -0,0 +1,185 @@
+// cmprule_test
+package cmprule
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testLatency struct {
+	Max time.Duration
+	Avg time.Duration
+}
+
+type testStructArith struct {
+	RxPkts  uint64
+	TxPkts  uint64
+	Errors  uint32
+	Drift   int
+	Rate    float32
+	Big     uint64
+	Latency testLatency
+	Start   time.Time
+	End     time.Time
+	Name    string
+	Peers   map[string]int `cmprule:"peers"`
+	Ports   []testPort
+	Doc     map[string]interface{}
+}
+
+var testArithInput = testStructArith{
+	RxPkts: 1000, TxPkts: 1010, Errors: 1, Drift: -7, Rate: 2.5, Big: math.MaxUint64,
+	Latency: testLatency{Max: 30 * time.Millisecond, Avg: 10 * time.Millisecond},
+	Start:   time.Date(2020, 3, 31, 15, 0, 0, 0, time.UTC),
+	End:     time.Date(2020, 3, 31, 15, 0, 4, 0, time.UTC),
+	Name:    "eth0",
+	Peers:   map[string]int{"peer-1": 3, "peer-2": 5},
+	Ports:   []testPort{{Errors: 0}, {Errors: 3}},
+	Doc:     map[string]interface{}{"rx": json.Number("2000"), "ratio": json.Number("0.5")},
+}
+
+var test_list_arith = []testResult{
+	{"RxPkts - TxPkts : == : -10", true, false},
+	{"TxPkts - RxPkts : <= : 10", true, false},
+	{"Errors / RxPkts : < : 0.001", false, false},
+	{"Errors / RxPkts : <= : 0.001", true, false},
+	{"Errors / RxPkts : ~= : 0.001 1%", true, false},
+	{"RxPkts % 3 : == : 1", true, false},
+	{"RxPkts * 2 + 1 : == : 2001", true, false},
+	{"RxPkts + 1 * 2 : == : 1002", true, false},
+	{"(RxPkts + 1) * 2 : == : 2002", true, false},
+	{"-Drift : == : 7", true, false},
+	{"abs(Drift) : < : 10", true, false},
+	{"abs(Drift - 3) : == : 10", true, false},
+	{"min(RxPkts, TxPkts) : == : 1000", true, false},
+	{"max(RxPkts, TxPkts, Rate) : == : 1010", true, false},
+	{"min(Rate, 3) : == : 2.5", true, false},
+	{"Rate * 2 : == : 5", true, false},
+	{"Big + 1 : > : 1e19", true, false},
+	{"Latency.Max : < : 2 * Latency.Avg", false, false},
+	{"Latency.Max : <= : 3 * Latency.Avg", true, false},
+	{"Latency.Max : <= : Latency.Avg * 3", true, false},
+	{"Latency.Max - Latency.Avg : == : 20ms", true, false},
+	{"Latency.Max / Latency.Avg : == : 3", true, false},
+	{"Latency.Max / 2 : == : 15ms", true, false},
+	{"abs(Latency.Avg - Latency.Max) : > : 15ms", true, false},
+	{"max(Latency.Avg, Latency.Max) : == : 30ms", true, false},
+	{"End - Start : < : 5s", true, false},
+	{"End : == : Start + 4s", true, false},
+	{"End : > : $Start + 5s", false, false},
+	{"TxPkts : >= : $RxPkts - 10", true, false},
+	{"TxPkts : <= : RxPkts + Errors * 10", true, false},
+	{"peers[peer-1] + peers[peer-2] : == : 8", true, false},
+	{`peers["peer-1"] * 2 : > : $peers[peer-2]`, true, false},
+	{"Ports[1].Errors - Ports[0].Errors : == : 3", true, false},
+	{"Doc.rx / RxPkts : == : 2", true, false},
+	{"Doc.ratio * 2 : == : 1", true, false},
+	{"RxPkts - TxPkts : > : RxPkts / 0", false, true},
+	{"RxPkts % 0 : == : 1", false, true},
+	{"Errors / Ports[0].Errors : < : 0.001", false, true},
+	{"Rate / 0 : == : 1", false, true},
+	{"Rate % 0 : == : 1", false, true},
+	{"Latency.Max / Ports[0].Errors : == : 1s", false, true},
+	{"Latency.Max / (Latency.Avg - Latency.Avg) : == : 1", false, true},
+	{"Name + 1 : == : 1", false, true},
+	{"Latency.Max + 1 : == : 1", false, true},
+	{"Latency.Max * Latency.Avg : == : 1", false, true},
+	{"End + Start : == : 1", false, true},
+	{"RxPkts + 1 : == : 1s", false, true},
+	{"RxPkts + NotExist : == : 1", false, true},
+}
+
+func TestArith(t *testing.T) {
+	tableTest(testArithInput, test_list_arith, t)
+}
+
+func TestArithParse(t *testing.T) {
+	invalid := []string{
+		"RxPkts - : == : 1",
+		"(RxPkts - TxPkts : == : 1",
+		"foo(RxPkts) - 1 : == : 1",
+		"abs(RxPkts, TxPkts) : == : 1",
+		"min(RxPkts) + 1 : == : 1",
+		"Ports[*].Errors + 1 : == : 1",
+		"RxPkts + 1x : == : 1",
+		"RxPkts : == : $RxPkts + $",
+	}
+	for _, r := range invalid {
+		rule := NewDefaultCMPRule()
+		err := rule.ParseRule(r)
+		var pe *ParseError
+		if !errors.As(err, &pe) {
+			t.Fatalf("rule %v returns %v, expect a ParseError", r, err)
+		}
+		t.Logf("rule %v returns expected error %v", r, err)
+	}
+	//field names contain operators without spaces are not expressions
+	for _, s := range []string{"peers.peer-1", "Sessions[a - b]", `Sessions["a - b"]`, "-120", "any(Ports).Errors", "1e-3"} {
+		if isArithExpr(s) {
+			t.Fatalf("%v is not an arithmetic expression", s)
+		}
+	}
+	rule := NewDefaultCMPRule()
+	if err := rule.ParseRule("RxPkts - TxPkts : <= : Errors * 5"); err != nil {
+		t.Fatal(err)
+	}
+	expected := "RxPkts - TxPkts = -10, expected <= Errors * 5, RxPkts = 1000, TxPkts = 1010, Errors = 1"
+	if e := rule.Explain(testArithInput); e != expected {
+		t.Fatalf("unexpected explanation %v", e)
+	}
+}
+
+func TestArithExpr(t *testing.T) {
+	cases := []struct {
+		expr   string
+		result bool
+	}{
+		{"(RxPkts - TxPkts) / RxPkts : < : 0.1", true},
+		{"(TxPkts - RxPkts) / RxPkts : > : 0.1 or abs(Drift) : > : 5", true},
+		{"((TxPkts - RxPkts) / RxPkts : > : 0.1) or (Drift : > : 5)", false},
+	}
+	for _, c := range cases {
+		e, err := ParseExpr(c.expr)
+		if err != nil {
+			t.Fatalf("failed to parse expression %v, %v", c.expr, err)
+		}
+		r, err := e.Compare(testArithInput)
+		if err != nil {
+			t.Fatalf("expression %v failed, %v", c.expr, err)
+		}
+		if r != c.result {
+			t.Fatalf("expression %v returns %v, expect %v", c.expr, r, c.result)
+		}
+	}
+}
+
+func TestArithValidate(t *testing.T) {
+	validateTest(reflect.TypeOf(testStructArith{}), []string{
+		"Errors / RxPkts : < : 0.001",
+		"Latency.Max : < : 2 * Latency.Avg",
+		"End - Start : < : 5s",
+		"Doc.rx / RxPkts : == : 2",
+	}, []string{
+		"RxPkts - TxPkts : == : 1s",
+		"End - Start : < : 5",
+		"Name + 1 : == : 1",
+		"Latency.Max : < : 2 * RxPkts",
+		"RxPkts + NotExist : == : 1",
+	}, t)
+}
+
+var test_list_arith_negative = []testResult{
+	{"Drift : == : -7", true, false},
+	{"Drift : == : -$Drift", false, false},
+	{"-Drift : == : -$Drift", true, false},
+	{"Drift : < : -$Drift", true, false},
+	{"Rate : > : -Inf", true, false},
+}
+
+func TestArithNegative(t *testing.T) {
+	tableTest(testArithInput, test_list_arith_negative, t)
+}
@@ -0,0 +1,42 @@
+// Copyright 2020 Hu Jun. All rights reserved.
+// This project is licensed under the terms of the MIT license.
+// license that can be found in the LICENSE file.
+
+package cmprule
+
+import "testing"
+
+// AssertRules evaluates each of rules against input as a subtest of t named by the rule,
+// each rule could be a single rule or a boolean expression of rules, see ParseExpr;
+// if any rule can't be parsed, t fails immediately by t.Fatalf before any rule is evaluated;
+// a rule which fails or could not be evaluated is reported by t.Errorf with actual and expected values.
+// it returns true if all rules passed
+func AssertRules(t *testing.T, input interface{}, rules ...string) bool {
+	t.Helper()
+	rs := NewRuleSet()
+	if err := rs.ParseRules(rules...); err != nil {
+		t.Fatalf("%v", err)
+	}
+	return AssertRuleSet(t, input, rs)
+}
+
+// AssertRuleSet is same as AssertRules, except the rules are in rs, like the ones loaded from a rule file;
+// the source of a rule is included in the report if it is known
+func AssertRuleSet(t *testing.T, input interface{}, rs *RuleSet) bool {
+	t.Helper()
+	passed := true
+	for i, rule := range rs.rules {
+		rule, src := rule, rs.sources[i]
+		passed = t.Run(rule.String(), func(t *testing.T) {
+			t.Helper()
+			r := newRuleResult(rule.String(), rule.CompareDetailed(input), src)
+			switch {
+			case r.Err != nil:
+				t.Errorf("rule %v could not be evaluated: %v", r.describe(), r.explanation())
+			case !r.Result:
+				t.Errorf("rule %v failed: %v", r.describe(), r.explanation())
+			}
+		}) && passed
+	}
+	return passed
+}
@@ -0,0 +1,75 @@
+// cmprule_test
+package cmprule
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// envAssertFail is set to run TestAssertRulesFailing in a sub process
+const envAssertFail = "CMPRULE_TEST_ASSERT_FAIL"
+
+func TestAssertRules(t *testing.T) {
+	input := testStruct{Num1: -120, Str1: "test1"}
+	if !AssertRules(t, input, "Num1 : == : -120", `Str1 : contain : "test" and not Num1 : > : 0`) {
+		t.Fatal("expect all rules passed")
+	}
+	rs := NewRuleSet()
+	if err := rs.Load(strings.NewReader("Num1 : in : -200 0\n"), "test.rules"); err != nil {
+		t.Fatal(err)
+	}
+	if !AssertRuleSet(t, input, rs) {
+		t.Fatal("expect all rules passed")
+	}
+}
+
+// TestAssertRulesFailing is expected to fail, it only runs in the sub process started by TestAssertRulesReport
+func TestAssertRulesFailing(t *testing.T) {
+	input := testStruct{Num1: -120}
+	switch os.Getenv(envAssertFail) {
+	case "fail":
+		AssertRules(t, input, "Num1 : > : 100", "Num1 : == : -120", "Num2 : == : 1")
+	case "parse":
+		AssertRules(t, input, "Num1 : == : -120", "Num1 : in : 1")
+		t.Log("not reached")
+	default:
+		t.Skip("only runs in sub process")
+	}
+}
+
+func TestAssertRulesReport(t *testing.T) {
+	cases := []struct {
+		env         string
+		expected    []string
+		notExpected []string
+	}{
+		{"fail", []string{
+			"--- FAIL: TestAssertRulesFailing/Num1_:_>_:_100",
+			"rule Num1 : > : 100 failed: Num1 = -120, expected > 100",
+			"--- PASS: TestAssertRulesFailing/Num1_:_==_:_-120",
+			"rule Num2 : == : 1 could not be evaluated: Num2: field Num2 doesn't exist",
+		}, nil},
+		{"parse", []string{"failed to parse rule Num1 : in : 1"}, []string{"not reached", "Num1_:_==_:_-120"}},
+	}
+	for _, c := range cases {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestAssertRulesFailing$", "-test.v")
+		cmd.Env = append(os.Environ(), envAssertFail+"="+c.env)
+		out, err := cmd.CombinedOutput()
+		t.Logf("%v: %s", c.env, out)
+		if err == nil {
+			t.Fatalf("%v: expect test to fail", c.env)
+		}
+		for _, e := range c.expected {
+			if !strings.Contains(string(out), e) {
+				t.Fatalf("%v: expect output contains %q", c.env, e)
+			}
+		}
+		for _, e := range c.notExpected {
+			if strings.Contains(string(out), e) {
+				t.Fatalf("%v: expect output not contains %q", c.env, e)
+			}
+		}
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright 2020 Hu Jun. All rights reserved.
+// This project is licensed under the terms of the MIT license.
+// license that can be found in the LICENSE file.
+
+// cmprule checks JSON result documents against a rule file.
+//
+// Usage:
+//
+//	cmprule [flags] rulefile [result.json ...]
+//
+// Each rule in rulefile is evaluated against each result document, if no document is specified, it is read from stdin.
+// The exit status is 0 if all rules passed, 1 if any rule failed or could not be evaluated, 2 for other errors.
+//
+// Flags:
+//
+//	-format string
+//		output format: text, json, junit or tap (default "text")
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/hujun-open/cmprule"
+)
+
+// exit status
+const (
+	exitPassed = 0
+	exitFailed = 1
+	exitError  = 2
+)
+
+// stdinName is the document name used for stdin
+const stdinName = "-"
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs the command with args, returns the exit status
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("cmprule", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	format := flags.String("format", "text", "output format: text, json, junit or tap")
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "usage: cmprule [flags] rulefile [result.json ...]\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	if flags.NArg() < 1 {
+		flags.Usage()
+		return exitError
+	}
+	write, ok := writers[*format]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown format %v\n", *format)
+		return exitError
+	}
+	rs := cmprule.NewRuleSet()
+	if err := rs.LoadFile(flags.Arg(0)); err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+	docs := flags.Args()[1:]
+	if len(docs) == 0 {
+		docs = []string{stdinName}
+	}
+	var results []cmprule.ReportSuite
+	for _, doc := range docs {
+		data, err := readDocument(doc, stdin)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return exitError
+		}
+		r, err := rs.EvaluateJSON(data)
+		if err != nil {
+			fmt.Fprintf(stderr, "%v: %v\n", doc, err)
+			return exitError
+		}
+		results = append(results, cmprule.ReportSuite{Name: doc, Results: r})
+	}
+	if err := write(stdout, results); err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitError
+	}
+	for _, r := range results {
+		if !cmprule.AllPassed(r.Results) {
+			return exitFailed
+		}
+	}
+	return exitPassed
+}
+
+// readDocument reads document name, which is stdin if name is "-"
+func readDocument(name string, stdin io.Reader) ([]byte, error) {
+	if name == stdinName {
+		return io.ReadAll(stdin)
+	}
+	return os.ReadFile(name)
+}
+
+// writers of each output format
+var writers = map[string]func(w io.Writer, results []cmprule.ReportSuite) error{
+	"text": writeText,
+	"json": writeJSON,
+	"junit": func(w io.Writer, results []cmprule.ReportSuite) error {
+		return cmprule.WriteJUnit(w, results...)
+	},
+	"tap": func(w io.Writer, results []cmprule.ReportSuite) error {
+		return cmprule.WriteTAP(w, results...)
+	},
+}
+
+// status returns the status of r as PASS, FAIL or ERROR
+func status(r cmprule.RuleResult) string {
+	switch {
+	case r.Err != nil:
+		return "ERROR"
+	case r.Result:
+		return "PASS"
+	default:
+		return "FAIL"
+	}
+}
+
+// writeText writes a line for each rule like "FAIL result.json rules.txt:3: Num1 = -120, expected > 100"
+func writeText(w io.Writer, results []cmprule.ReportSuite) error {
+	passed, total := 0, 0
+	for _, doc := range results {
+		for _, r := range doc.Results {
+			total++
+			if r.Passed() {
+				passed++
+				_, err := fmt.Fprintf(w, "%v %v %v: %v\n", status(r), doc.Name, r.Source, r.Rule)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			_, err := fmt.Fprintf(w, "%v %v %v: %v\n", status(r), doc.Name, r.Source, r.Detail)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	_, err := fmt.Fprintf(w, "%d/%d passed\n", passed, total)
+	return err
+}
+
+// jsonResult is the result of a rule in json format
+type jsonResult struct {
+	Document    string `json:"document"`
+	Source      string `json:"source"`
+	Rule        string `json:"rule"`
+	Status      string `json:"status"`
+	Explanation string `json:"explanation"`
+	Error       string `json:"error,omitempty"`
+}
+
+// writeJSON writes an array of results
+func writeJSON(w io.Writer, results []cmprule.ReportSuite) error {
+	list := []jsonResult{}
+	for _, doc := range results {
+		for _, r := range doc.Results {
+			jr := jsonResult{
+				Document:    doc.Name,
+				Source:      r.Source.String(),
+				Rule:        r.Rule,
+				Status:      status(r),
+				Explanation: r.Detail.String(),
+			}
+			if r.Err != nil {
+				jr.Error = r.Err.Error()
+			}
+			list = append(list, jr)
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(list)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRun(t *testing.T) {
+	dir, err := os.MkdirTemp("", "cmprule")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	rules := writeFile(t, dir, "test.rules", "# checks\nrx : > : 100\nname : same : \"a\"\nrate : > : 1\n")
+	passRules := writeFile(t, dir, "pass.rules", "rx : > : 100\n")
+	badRules := writeFile(t, dir, "bad.rules", "rx : in : 1\n")
+	doc := writeFile(t, dir, "result.json", `{"rx": 200, "name": "b"}`)
+	cases := []struct {
+		args   []string
+		stdin  string
+		status int
+		out    []string
+	}{
+		{[]string{passRules, doc}, "", exitPassed, []string{"PASS " + doc + " " + passRules + ":1: rx : > : 100", "1/1 passed"}},
+		{[]string{passRules}, `{"rx": 1}`, exitFailed, []string{"FAIL - " + passRules + ":1: rx = 1, expected > 100", "0/1 passed"}},
+		{[]string{rules, doc}, "", exitFailed, []string{
+			"PASS " + doc + " " + rules + ":2: rx : > : 100",
+			"FAIL " + doc + " " + rules + `:3: name = "b", expected same "a"`,
+			"ERROR " + doc + " " + rules + ":4: rate: key rate doesn't exist",
+			"1/3 passed",
+		}},
+		{[]string{"--format=json", rules, doc}, "", exitFailed, []string{`"status": "ERROR"`, `"source": "` + rules + `:3"`}},
+		{[]string{"-format", "junit", rules, doc, "-"}, `{"rx": 1}`, exitFailed, []string{`<testsuites tests="6" failures="2" errors="3">`}},
+		//errors
+		{[]string{}, "", exitError, nil},
+		{[]string{"--format=xml", rules}, "", exitError, nil},
+		{[]string{badRules, doc}, "", exitError, nil},
+		{[]string{rules, filepath.Join(dir, "notexist.json")}, "", exitError, nil},
+		{[]string{rules}, "{", exitError, nil},
+	}
+	for _, c := range cases {
+		var stdout, stderr bytes.Buffer
+		status := run(c.args, strings.NewReader(c.stdin), &stdout, &stderr)
+		t.Logf("args %v, status %d\nstdout:\n%v\nstderr:\n%v", c.args, status, stdout.String(), stderr.String())
+		if status != c.status {
+			t.Fatalf("args %v expect status %d, got %d", c.args, c.status, status)
+		}
+		for _, o := range c.out {
+			if !strings.Contains(stdout.String(), o) {
+				t.Fatalf("args %v expect output contains %q", c.args, o)
+			}
+		}
+		if status == exitError && stderr.Len() == 0 {
+			t.Fatalf("args %v expect error message", c.args)
+		}
+	}
+}
+
+func TestWriteFormats(t *testing.T) {
+	dir, err := os.MkdirTemp("", "cmprule")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	rules := writeFile(t, dir, "test.rules", "rx : > : 100\nrx : < : 100\n")
+	var stdout, stderr bytes.Buffer
+	run([]string{"-format", "json", rules}, strings.NewReader(`{"rx": 200}`), &stdout, &stderr)
+	var list []jsonResult
+	if err := json.Unmarshal(stdout.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].Status != "PASS" || list[1].Status != "FAIL" || list[1].Explanation != "rx = 200, expected < 100" {
+		t.Fatalf("unexpected json output %+v", list)
+	}
+	stdout.Reset()
+	run([]string{"-format", "junit", rules}, strings.NewReader(`{"rx": 200}`), &stdout, &stderr)
+	if !strings.Contains(stdout.String(), `<failure message="rx = 200, expected &lt; 100">`) {
+		t.Fatalf("unexpected junit output %v", stdout.String())
+	}
+	stdout.Reset()
+	run([]string{"-format", "tap", rules}, strings.NewReader(`{"rx": 200}`), &stdout, &stderr)
+	if !strings.Contains(stdout.String(), "1..2\nok 1 - -: rx : > : 100\nnot ok 2 - -: rx : < : 100\n") {
+		t.Fatalf("unexpected tap output %v", stdout.String())
+	}
+}
@@ -35,8 +35,12 @@ cmprule support following golang types of a struct field, along with correspondi
 	- string
 	- time.Time
 	- time.Duration
-	- net.IP
+	- net.IP, netip.Addr: IP address
+	- net.IPNet, *net.IPNet, netip.Prefix: IP prefix
+	- bool
 	- struct: this is specifically means nested struct
+	- slice, array and map of above types, see field_name below
+	- json.Number, compared exactly as int64 or uint64 if both the field and the values are integers, otherwise as float64
 
 
 Default Rule Format
@@ -51,6 +55,27 @@ The default rule format is following:
 
 field_name could have format as "aa.bb.cc" to support nested struct
 
+By default, a name in field_name matches the name in the field's cmprule struct tag first, then the Go field name:
+	type ExampleStruct struct {
+		RxPkts uint `cmprule:"rx_packets"` //could be referred as "rx_packets" or "RxPkts"
+	}
+use CMPRule.SetFieldNaming() to match Go field name only, fall back to json struct tag, or match case-insensitively.
+
+field_name could specify element of a slice or array field:
+
+	- "Ports[3].Speed": Speed of the 4th element of Ports
+	- "Ports[*].State" or "all(Ports).State": every element of Ports must pass the rule
+	- "any(Ports).Errors": at least one element of Ports must pass the rule
+	- "none(Ports).Errors": no element of Ports could pass the rule
+	- wildcards could be nested, like "Ports[*].Queues[*]"; for an empty slice, all and none return true, any returns false
+
+field_name could specify value of a map field, wildcard and quantifiers above iterate all values of the map:
+
+	- `Sessions["peer1"].State` or "Sessions[peer1].State" or "Sessions.peer1.State": State of the value with key "peer1"
+	- "IntMap[3]": the key is parsed according to the key type of the map,
+	supported key types are string, integer, float, bool, interface{} and types implement encoding.TextUnmarshaler
+	- a double-quoted key could contain any character, use a backslash '\' to escape '"'
+
 Different type has different Op and Value format:
 
 	- Numberic type: this includes all int/uint/float/time.Time/Time.Duration type in Golang
@@ -66,9 +91,17 @@ Different type has different Op and Value format:
 			- Op: is, not
 			- Value: a list of numbers, sperated by space
 			- example: 'Stat1 : is : 100 200 300 400'
+		- Approximate value: return true if the field value is/isn't within the tolerance of the value,
+		only for int/uint/float type
+			- Op: ~=, !~=, approx, notapprox
+			- Value: a number and a tolerance, sperated by space; an absolute tolerance is like ±0.01 or +-0.01,
+			a relative tolerance is a percentage of the value, like 1%
+			- example: 'Rate : ~= : 12.5 ±0.01', 'Rate : approx : 12.5 1%'
+			- NaN only matches NaN, an infinity only matches the infinity of same sign
 		- Notes:
-			- for time.Time, the string format is like const TIMEFMTSTR
+			- for time.Time, the string format is like const TimeFMTStr or time.RFC3339, see Time below
 			- for time.Duration, the string format is whatever supported by time.ParseDuration()
+			- for float types, NaN == NaN is true, so 'Rate : != : NaN' checks the field is a number
 	- string:
 		- a list of strings: return true if the field value is one/none of the list
 			- Op: same, differ
@@ -78,31 +111,283 @@ Different type has different Op and Value format:
 			- Op: contain, notcontain
 			- Value: a list of double-quoted string, seperate by space
 			- example: 'Lastlog : contain : "warning" "fail"
+		- a list of strings: return true if the field value starts/ends with any string of the list
+			- Op: prefix, suffix
+			- Value: a list of double-quoted string, seperate by space
+			- example: 'Version : prefix : "v1." "v2."'
+		- a list of regular expressions: return true if the field value matches/doesn't match any regexp of the list
+			- Op: match, notmatch
+			- Value: a list of double-quoted regexp in Go syntax, seperate by space; they are compiled in ParseRule
+			- example: 'Lastlog : notmatch : "(?m)^ERROR" "panic:"'
+		- a list of shell patterns: return true if the field value matches/doesn't match any pattern of the list
+			- Op: like, notlike
+			- Value: a list of double-quoted pattern, seperate by space, see path.Match for pattern syntax
+			- example: 'Hostname : like : "core-*-rtr??" "edge-[0-9]*"'
+		- case-insensitive variants of operators: isame, idiffer, icontain, inotcontain, iprefix, isuffix, imatch, inotmatch
 		- note: if the string in the value contain '"', use a backslash '\' to escape; like '\"'
 
-	- net.IP:
-		- a list of strings: return true if the field value is  within/not within to one/any prefix of the list
+	- bool:
+		- single value:
+			- Op: ==,!=
+			- Value: a single bool value
+			- example: 'LinkUp : == : true'
+		- A list of values: return true if the field value is one/none of the list
+			- Op: is, not
+			- Value: a list of bool values, sperated by space
+			- example: 'LinkUp : is : true'
+		- note: by default, value is parsed by strconv.ParseBool, use SetParseBoolFunc to accept other format like "yes/no"
+
+	- IP address: net.IP, netip.Addr
+		- a list of prefixes: return true if the field value is within one/none of the prefixes of the list
 			- Op: within/notwithin
 			- Value: a list of IP prefixes, seperate by space
 			- example: 'MgmtAddr : within : 1.1.1.1/24 2001:dead::1/64'
+		- single value:
+			- Op: ==,!=
+			- Value: a single IP address
+			- example: 'Gateway : == : 10.0.0.1'
+		- A list of values: return true if the field value is one/none of the list
+			- Op: is, not
+			- Value: a list of IP addresses, sperated by space
+			- example: 'NextHop : is : 10.0.0.1 10.0.0.2'
+		- Range value: return true if the field value is within/not within the range, min and max are of same address family
+			- Op: in, notin
+			- Value: min,max IP address, sperated by space
+			- example: 'ClientAddr : in : 10.0.0.1 10.0.0.50'
+		- address properties: return true if the field value has/hasn't the property according to the bool value
+			- Op: is4 (IPv4, including IPv4-mapped IPv6 address), is6, private, loopback, multicast
+			- Value: a single bool value
+			- example: 'MgmtAddr : private : true'
+		- note: use SetParseIPFunc to accept other format of IP address
+	- IP prefix: net.IPNet, *net.IPNet, netip.Prefix; the prefix of the field value is masked, like 10.1.1.1/8 is 10.0.0.0/8
+		- Op: within/notwithin, return true if the field value is a subnet of, or same as one/none of the prefixes of the list
+		- Op: ==,!=,is,not, same as IP address, except values are IP prefixes
+		- Op: is4, is6, same as IP address
+		- example: 'Subnet : within : 10.0.0.0/8', 'Route : is : 0.0.0.0/0 ::/0'
+
+Unit
+
+Values of numberic types could have a SI or IEC prefix and a unit, by setting ParseUnitInt64, ParseUnitUint64 and ParseUnitFloat64
+as the parse functions:
+	cmp.SetParseNumInt64Func(cmprule.ParseUnitInt64)
+	cmp.SetParseUint64Func(cmprule.ParseUnitUint64)
+	cmp.SetParseFloat64Func(cmprule.ParseUnitFloat64)
+	cmp.ParseRule("Throughput : >= : 9.5Gbps")
+
+- prefixes are k (or K), M, G, T, P, E for powers of 1000, and Ki, Mi, Gi, Ti, Pi, Ei for powers of 1024
+
+- units are b (bit, bits), B (byte, bytes), bps (b/s, bit/s), Bps (B/s) and pps, a value is converted to the unit without prefix,
+like "512MiB" is 536870912; a prefix could also be used alone, like "1.5k"
+
+- a field could declare its unit by option unit in cmprule struct tag, a value with a different unit is rejected by Compare and Validate,
+a value without unit is in the unit of the field:
+	type PortStats struct {
+		RxRate uint64 `cmprule:"rx_rate,unit=bps"` //"rx_rate : >= : 10MB" is an error
+		Mem    uint64 `cmprule:",unit=B"`
+	}
+
+Time
+
+time.Time fields are compared in nanosecond precision, times in different zones are compared by the instant:
+	Stamp : > : 2020/03/31T15:00:00.250
+	Stamp : in : 2020-03-31T15:00:00+08:00 2020-03-31T16:00:00.5+08:00
+
+- by default, a value is parsed with layouts TimeFMTStr, time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05" and "2006-01-02"
+in order, fractional seconds are accepted after the seconds of any layout; use CMPRule.SetTimeLayouts to change the layouts
+
+- a value without zone is in UTC, use CMPRule.SetTimeLocation to change it
+
+- a value could be relative to the time of comparison: now, today, yesterday or tomorrow,
+optionally followed by + or - and a time.Duration without space, like "now-5m" or "today+8h";
+today is the midnight in the location set by SetTimeLocation, and the current time is from time.Now, use CMPRule.SetClock to change it:
+	Stamp : >= : now-5m
+
+Field Reference
+
+The value of a rule with operator ==,!=,>=,<=,>,< could refer to another field of the input by "$" followed by its field name,
+the referred field is resolved at compare time like the compared field:
+	TxPkts : <= : $RxPkts
+	End : > : $Start
+
+- the referred field must be a single field, wildcard and quantifiers are not allowed in its name
+
+- a value starts with "$" of other operators, like "Num : in : $Min 10", is rejected by ParseRule
+
+- both fields must be of same kind: numbers (int/uint/float/json.Number of any size, compared exactly for integers),
+time.Duration, time.Time, string, bool or IP address (net.IP or netip.Addr); bool and IP address fields could only be compared by == and !=
+
+- the explanation includes the value of the referred field, like "TxPkts = 90, expected >= $RxPkts, RxPkts = 100"
+
+Arithmetic Expression
+
+The field name, and the value of a rule with operator ==,!=,>=,<=,>,<, could be an arithmetic expression of fields and numbers:
+	RxPkts - TxPkts : <= : 10
+	Errors / RxPkts : < : 0.001
+	Latency.Max : < : 2 * Latency.Avg
+	abs(End - Start) : < : 5s
+
+- operators are +, -, *, /, % and parentheses, functions are abs(x), min(x, y, ...) and max(x, y, ...)
+
+- binary operators must be separated by spaces, so that "peer-1" is still a field name;
+a field in an expression could be prefixed by "$", a negated field in the value must be, like "-$Drift"
+
+- integers of any type are promoted to int64, and to float64 if any operand is a float or the result overflows;
+/ always results in float64, so "Errors / RxPkts" is a ratio
+
+- time.Duration could be added to or subtracted from time.Duration or time.Time, and multiplied or divided by a number;
+time.Time - time.Time results in time.Duration, time.Duration / time.Duration results in float64
+
+- a field in an expression must be a single field of number, time.Duration or time.Time, wildcard is not allowed;
+the explanation includes the value of every field in the expressions
+
+Boolean Expression
+
+Rules could be combined into a boolean expression with "and", "or", "not" and parentheses,
+by using ParseExpr:
+	(RxPkts : > : 0) and not (Errors : > : 10 or Drops : in : 1 100)
+
+- precedence from high to low: not, and, or
+
+- evaluation is from left to right, and stops as soon as the result is known
+
+- a rule in the expression ends at keyword "and"/"or" or an unmatched ')';
+keywords within a double-quoted string are part of the rule
+
+- keywords are lower case, a word followed by ':', '.' or '[' is a field name, like "or : == : 1"
+
+JSON Document
+
+Besides a struct, input of Compare could be a JSON document decoded into interface{} by encoding/json,
+a JSON object is accessed like a map, a JSON array is accessed like a slice;
+CompareJSON decodes numbers as json.Number, so that an integer is compared without losing precision:
+	cmp.ParseRule(`sessions.peer1.state : same : "up"`)
+	result, err := cmp.CompareJSON([]byte(`{"sessions": {"peer1": {"state": "up"}}}`))
+if the document is an array, use empty field name with index or quantifier, like "[0].id" or "any().id".
+
+Explanation
+
+CompareDetailed returns a CompareResult, which includes resolved fields, actual values and expected values;
+Explain returns a human-readable explanation of the result:
+	cmp.ParseRule("Num1 : > : 100")
+	cmp.Explain(example) //returns "Num1 = -120, expected > 100"
+
+Compiled Rule
+
+Compile parses a rule into a Rule, which can't be changed after compiled,
+so it is safe to be shared by multiple goroutines concurrently:
+	r, err := cmprule.Compile("Stat1 : >= : 50")
+	if err!=nil {
+		//process error here
+	}
+	result, err := r.Compare(example1)
+
+Values of a rule are parsed lazily on first compare of a numberic, time.Duration or time.Time field,
+into int64, uint64, float64 or time according to the field type, and cached for each of these types;
+relative time values like "now-5m" are resolved on every compare.
+
+Validation
+
+By default, errors like an unknown field or an unparsable value are returned by Compare;
+use ParseRuleFor, CompileFor or Validate to check a rule against a struct type when it is parsed:
+	err := cmp.ParseRuleFor("Stat1 : >= : 50", reflect.TypeOf(ExampleStruct{}))
+a field of interface type, like a value in a decoded JSON document, is not checked.
+
+Errors
+
+An error caused by the rule itself is one of following types, which could be checked by errors.As:
+
+	- *ParseError: the rule, expression or a value in it can't be parsed
+	- *FieldNotFoundError: a field, map key or index in the rule doesn't exist in the input
+	- *TypeMismatchError: the type of the field doesn't support the rule
+	- *InvalidOperatorError: the operator is unknown or invalid for the type of the field
+
+each of them includes the rule text, the offending token and its column in the rule:
+	var perr *cmprule.ParseError
+	if errors.As(err, &perr) {
+		//perr.Token is at perr.Column of perr.Rule
+	}
+for a field in the input which is a nil pointer, the error wraps ErrNilPoint.
+
+Rule Set
+
+Multiple rules or expressions could be evaluated against the same struct by using RuleSet:
+	rs := NewRuleSet()
+	err := rs.ParseRules("Stat1 : >= : 50", "Stat2 : in : 10 30")
+	if err!=nil {
+		//process error here
+	}
+	for _, r := range rs.Evaluate(example1) {
+		//r.Rule is the rule text, r.Result is the compare result, r.Err is the error, r.Detail is the CompareResult
+	}
+
+Rule File
+
+Rules could be loaded from a rule file into a RuleSet by using RuleSet.LoadFile:
+	# lines start with '#' are comments, blank lines are ignored
+	Stat1 : >= : 50
+	Result : same : "Passed without error" \
+		"Passed with error"
+	include common.rules
+
+- each line is a rule or a boolean expression of rules, parsed like RuleSet.ParseRules
+
+- a line ends with '\' is joined with the next line, comments and blank lines in between are ignored
+
+- "include path" loads rules from another rule file, a relative path is relative to the directory of the including file;
+use double quote if the path contains space
+
+- the file name and line number of each rule is kept as RuleResult.Source, and included in parse errors
+
+Structured Rule Definition
+
+Besides the text form, a rule could be defined as a RuleDef, and parsed by CMPRule.ParseRuleDef or RuleSet.AddRuleDefs;
+values in a RuleDef are used as they are, so strings need no quoting or escaping.
+A list of RuleDef could be decoded from JSON by ParseRuleDefsJSON:
+	[{"field": "Stat1", "op": ">=", "value": 50}, {"field": "Stat2", "op": "in", "value": {"min": 10, "max": 30}}]
+or from YAML by ParseRuleDefsYAML:
+	- field: Result
+	  op: same
+	  value: ["Passed without error", "Passed with error"]
+only a subset of YAML is supported: block and flow sequences/mappings, plain and quoted scalars, and comments.
+CMPRule.RuleDef returns the definition of a parsed rule, and RuleDef.String returns the text form of a definition.
+
+Report
+
+Results of a RuleSet, or of CMPRules evaluated by EvaluateRules, could be written as a JUnit XML report by WriteJUnit,
+or a TAP report by WriteTAP; a failed rule is reported with its actual and expected values:
+	err := cmprule.WriteJUnit(os.Stdout, cmprule.ReportSuite{Name: "stats", Results: rs.Evaluate(example1)})
+
+Testing
+
+AssertRules checks a value in a go test, each rule is run as a subtest, a failed rule is reported with its actual and expected values,
+a rule can't be parsed stops the test:
+	func TestStats(t *testing.T) {
+		cmprule.AssertRules(t, getStats(), "Stat1 : >= : 50", "Stat2 : in : 10 30")
+	}
+AssertRuleSet does the same for a RuleSet, like one loaded from a rule file.
 
 Custom Rule Format
 
 Optionally, the rule format could be customized by defining new parsing
 function and pass it to CMPRule instances, by using CMPRule.SetxxxFunc(),
 See corresponding function's doc for details.
+For example, to accept numbers with a suffix like "1.5k" or "2M" for all numberic types,
+set the same format with SetParseNumInt64Func, SetParseUint64Func and SetParseFloat64Func.
 
 */
 package cmprule
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net"
+	"path"
 	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -122,10 +407,28 @@ const (
 	opStrDiffer     = "differ"
 	opStrContain    = "contain"
 	opStrNotContain = "notcontain"
+	opStrPrefix     = "prefix"
+	opStrSuffix     = "suffix"
+	opStrMatch      = "match"
+	opStrNotMatch   = "notmatch"
+	opStrLike       = "like"
+	opStrNotLike    = "notlike"
 	opIPWithin      = "within"
 	opIPNotWithin   = "notwithin"
 )
 
+// case-insensitive variants of string compare operators
+const (
+	opStrSameI       = "isame"
+	opStrDifferI     = "idiffer"
+	opStrContainI    = "icontain"
+	opStrNotContainI = "inotcontain"
+	opStrPrefixI     = "iprefix"
+	opStrSuffixI     = "isuffix"
+	opStrMatchI      = "imatch"
+	opStrNotMatchI   = "inotmatch"
+)
+
 const (
 	valueSingle = iota
 	valueRange
@@ -149,10 +452,32 @@ func detectType(op string) int {
 const (
 	prepareTypeNum = iota
 	prepareTypeDuration
-	prepareTypeTimestamp
-	prepareTypeNotPrepared
 )
 
+// int64Values is rule values parsed into int64 for a type
+type int64Values struct {
+	single int64
+	min    int64
+	max    int64
+	list   []int64
+}
+
+// uint64Values is rule values parsed into uint64 for a type
+type uint64Values struct {
+	single uint64
+	min    uint64
+	max    uint64
+	list   []uint64
+}
+
+// float64Values is rule values parsed into float64 for a type
+type float64Values struct {
+	single float64
+	min    float64
+	max    float64
+	list   []float64
+}
+
 // TimeFMTStr is the time format string used by default parse time function
 const TimeFMTStr = "2006/01/02T15:04:05"
 
@@ -204,6 +529,14 @@ func defaultParseNumInt64Func(numstr string) (int64, error) {
 	return strconv.ParseInt(numstr, 0, 64)
 }
 
+func defaultParseNumUint64Func(numstr string) (uint64, error) {
+	return strconv.ParseUint(numstr, 0, 64)
+}
+
+func defaultParseNumFloat64Func(numstr string) (float64, error) {
+	return strconv.ParseFloat(numstr, 64)
+}
+
 func defaultParseDurationInt64Func(durationstr string) (int64, error) {
 	d, err := time.ParseDuration(durationstr)
 	if err != nil {
@@ -226,86 +559,50 @@ func defaultParseIPNetListFunc(listval string) ([]*net.IPNet, error) {
 
 }
 
-func defaultParseTimeInt64Func(timestr string) (int64, error) {
-	t, err := time.Parse(TimeFMTStr, timestr)
-	if err != nil {
-		return 0, err
-	}
-	return t.Unix(), nil
-}
-
-// use "." as seperator, like "aaa.bbb.ccc"
-func defaultParseNestedStructFunc(fieldName string) []string {
-	return strings.Split(fieldName, ".")
-}
-
-// return a struct field based on field_name_list, which is hierchical name list
-func getStructField(inputStruct interface{}, fieldNameList []string) (interface{}, error) {
-	currentStruct := inputStruct
-	listLen := len(fieldNameList)
-	var currentType reflect.Type
-	var currentVal reflect.Value
-	var i int
-	var fname string
-	for i, fname = range fieldNameList {
-		currentType = reflect.TypeOf(currentStruct)
-		currentVal = reflect.ValueOf(currentStruct)
-		//if the field is a pointer, return the interface{} it points to
-		if currentType.Kind() == reflect.Ptr {
-			if currentVal.IsZero() {
-				return nil, fmt.Errorf("%v is %w", currentType, ErrNilPoint)
-			}
-			currentStruct = reflect.Indirect(currentVal).Interface()
-			currentType = reflect.TypeOf(currentStruct)
-			currentVal = reflect.ValueOf(currentStruct)
-		}
-
-		if currentType.Kind() != reflect.Struct {
-			return nil, fmt.Errorf("%v is not a struct", fieldNameList[i-1])
-		}
-
-		if _, ok := currentType.FieldByName(fname); !ok {
-			return nil, fmt.Errorf("field %v doesn't exist in %v", fname, currentType.String())
-		}
-		if currentType.Kind() != reflect.Struct && i != listLen-1 {
-			return nil, fmt.Errorf("%v is not a struct", currentType.String())
-		}
-		currentStruct = currentVal.FieldByName(fname).Interface()
-	}
-	if reflect.TypeOf(currentStruct).Kind() == reflect.Ptr {
-		if reflect.ValueOf(currentStruct).IsZero() {
-			return nil, fmt.Errorf("%v is %w", reflect.TypeOf(currentStruct), ErrNilPoint)
-		}
-		return reflect.Indirect(reflect.ValueOf(currentStruct)).Interface(), nil
-	}
-	return currentStruct, nil
-}
-
 // CMPRule represents a single compare rule
 type CMPRule struct {
+	rawRule                string
 	ruleFieldName          string
 	ruleOp                 string
 	ruleVal                string
 	divideRuleFunc         func(rule string) (string, string, string, error)
 	parseRangeFunc         func(rangeval string) (string, string, error)
+	parseApproxFunc        func(approxval string) (string, string, error)
 	parseNumListFunc       func(listval string) ([]string, error)
 	parseIPNetListFunc     func(listval string) ([]*net.IPNet, error)
+	parseIPFunc            func(ipstr string) (net.IP, error)
 	parseStrListFunc       func(listval string) ([]string, error)
+	parseGlobListFunc      func(listval string) ([]string, error)
 	parseNumInt64Func      func(numstr string) (int64, error)
+	parseNumUint64Func     func(numstr string) (uint64, error)
+	parseNumFloat64Func    func(numstr string) (float64, error)
 	parseDurationInt64Func func(durationstr string) (int64, error)
-	parseTimeInt64Func     func(timestr string) (int64, error)
+	parseTimeFunc          func(timestr string) (time.Time, error)
+	timeLayouts            []string
+	timeLocation           *time.Location
+	clock                  func() time.Time
 	parseFieldNamFunc      func(field_name string) []string
-	prepareInt64Type       int
+	parseBoolFunc          func(boolstr string) (bool, error)
+	preparedLock           sync.Mutex
+	preparedInt64          map[int]*int64Values
+	preparedUint64         map[int]*uint64Values
+	preparedFloat64        map[int]*float64Values
+	preparedTime           *timeValues
+	preparedIP             *ipValues
+	fieldUnits             map[reflect.Type]string
 	numMinStr              string
 	numMaxStr              string
 	numListStr             []string
-	int64Single            int64
-	int64Min               int64
-	int64Max               int64
-	int64List              []int64
+	approxValStr           string
+	approxTolStr           string
+	approx                 approxValue
 	strList                []string
+	regexpList             []*regexp.Regexp
 	ipNetList              []*net.IPNet
-	fieldNameList          []string
+	fieldPath              []pathSegment
+	fieldExpr              arithNode
+	ref                    *fieldRef
+	resolver               fieldResolver
 }
 
 // NewDefaultCMPRule Returns a CMPRule instance with default parse functions
@@ -314,131 +611,362 @@ func NewDefaultCMPRule() *CMPRule {
 	r.divideRuleFunc = defaultDivideFunc
 	r.parseNumListFunc = defaultParseNumListFunc
 	r.parseRangeFunc = defaultParseRangeFunc
+	r.parseApproxFunc = defaultParseApproxFunc
 	r.parseStrListFunc = defaultParseStrListFunc
+	r.parseGlobListFunc = defaultParseStrListFunc
 	r.parseNumInt64Func = defaultParseNumInt64Func
+	r.parseNumUint64Func = defaultParseNumUint64Func
+	r.parseNumFloat64Func = defaultParseNumFloat64Func
 	r.parseDurationInt64Func = defaultParseDurationInt64Func
-	r.parseTimeInt64Func = defaultParseTimeInt64Func
+	r.parseTimeFunc = r.parseTimeInLayouts
+	r.timeLayouts = defaultTimeLayouts
+	r.timeLocation = time.UTC
+	r.clock = time.Now
 	r.parseIPNetListFunc = defaultParseIPNetListFunc
+	r.parseIPFunc = defaultParseIPFunc
 	r.parseFieldNamFunc = defaultParseNestedStructFunc
-	r.prepareInt64Type = prepareTypeNotPrepared
+	r.parseBoolFunc = strconv.ParseBool
+	r.resolver.naming = NamingTag
 	return r
 }
 
 // ParseRule Parses a string to get a rule, see package doc for the default format of the rawrule string
 func (cmprule *CMPRule) ParseRule(rawrule string) (err error) {
+	cmprule.rawRule = rawrule
+	cmprule.ClearPreparedInt64Value()
 	cmprule.ruleFieldName, cmprule.ruleOp, cmprule.ruleVal, err = cmprule.divideRuleFunc(rawrule)
+	if err != nil {
+		return &ParseError{Rule: rawrule, Token: rawrule, Column: 0, Msg: "invalid rule", Err: err}
+	}
 	switch cmprule.ruleOp {
 	case opNumIN, opNumNotIN:
 		cmprule.numMinStr, cmprule.numMaxStr, err = cmprule.parseRangeFunc(cmprule.ruleVal)
 	case opNumIs, opNumNot:
 		cmprule.numListStr, err = cmprule.parseNumListFunc(cmprule.ruleVal)
-	case opStrContain, opStrDiffer, opStrNotContain, opStrSame:
+	case opStrContain, opStrDiffer, opStrNotContain, opStrSame, opStrPrefix, opStrSuffix,
+		opStrContainI, opStrDifferI, opStrNotContainI, opStrSameI, opStrPrefixI, opStrSuffixI:
+		cmprule.strList, err = cmprule.parseStrListFunc(cmprule.ruleVal)
+	case opStrMatch, opStrNotMatch, opStrMatchI, opStrNotMatchI:
 		cmprule.strList, err = cmprule.parseStrListFunc(cmprule.ruleVal)
+		if err == nil {
+			err = cmprule.compileRegexpList()
+		}
+	case opStrLike, opStrNotLike:
+		cmprule.strList, err = cmprule.parseGlobListFunc(cmprule.ruleVal)
+		if err == nil {
+			err = cmprule.checkGlobList()
+		}
 	case opIPWithin, opIPNotWithin:
 		cmprule.ipNetList, err = cmprule.parseIPNetListFunc(cmprule.ruleVal)
+	case opIPIs4, opIPIs6, opIPPrivate, opIPLoopback, opIPMulticast:
+	case opNumApprox, opNumNotApprox, opNumApproxWord, opNumNotApproxWord:
+		cmprule.approxValStr, cmprule.approxTolStr, err = cmprule.parseApproxFunc(cmprule.ruleVal)
+		if err == nil {
+			err = cmprule.parseApprox()
+		}
+	case opNumEq, opNumNotEq, opNumL, opNumLE, opNumS, opNumSE:
+	default:
+		return cmprule.opError("")
+	}
+	values := strings.Fields(cmprule.ruleVal)
+	if isStrOp(cmprule.ruleOp) {
+		//a double-quoted string is not a field reference
+		values = []string{strings.TrimSpace(cmprule.ruleVal)}
+	}
+	if referr := cmprule.checkFieldRefOp(values); referr != nil {
+		return referr
+	}
+	if err == nil {
+		err = cmprule.parseFieldRef()
+	}
+	if err != nil {
+		return cmprule.valueError(cmprule.ruleVal, "invalid value", err)
 	}
-	cmprule.prepareInt64Type = prepareTypeNotPrepared
-	cmprule.fieldNameList = cmprule.parseFieldNamFunc(cmprule.ruleFieldName)
-	return
+	return cmprule.parseField()
 }
 
-func (cmprule *CMPRule) prepareInt64(f func(string) (int64, error)) (err error) {
+// parseField parses the field name of the rule, which could be an arithmetic expression of fields,
+// including a negated field like "-Drift"
+func (cmprule *CMPRule) parseField() (err error) {
+	cmprule.fieldPath, cmprule.fieldExpr = nil, nil
+	if isArithExpr(cmprule.ruleFieldName) || strings.HasPrefix(cmprule.ruleFieldName, arithSub) {
+		cmprule.fieldExpr, err = parseArith(cmprule.ruleFieldName, cmprule.parseFieldNamFunc)
+	} else {
+		cmprule.fieldPath, err = parseFieldPath(cmprule.parseFieldNamFunc(cmprule.ruleFieldName))
+	}
+	return cmprule.annotateError(err)
+}
+
+// walkRuleField resolves the field of the rule in input, and calls visit with the value of each field;
+// if the field name is an arithmetic expression, visit is called with its value, and ref is called with every resolved operand field
+func (cmprule *CMPRule) walkRuleField(input interface{}, visit visitFunc, ref func(FieldValue)) (bool, error) {
+	if cmprule.fieldExpr == nil {
+		return cmprule.resolver.walkField(input, "", cmprule.fieldPath, visit)
+	}
+	v, err := cmprule.fieldExpr.eval(cmprule.resolver, input, ref)
+	if err != nil {
+		return false, err
+	}
+	return visit(cmprule.ruleFieldName, v)
+}
+
+// String returns the raw rule text last parsed by ParseRule
+func (cmprule *CMPRule) String() string {
+	return cmprule.rawRule
+}
+
+// prepareInt64 returns rule values parsed by f for prepareType,
+// the parsed values are cached, so that they are parsed only once for each prepareType
+func (cmprule *CMPRule) prepareInt64(prepareType int, f func(string) (int64, error)) (*int64Values, error) {
+	cmprule.preparedLock.Lock()
+	defer cmprule.preparedLock.Unlock()
+	if values, ok := cmprule.preparedInt64[prepareType]; ok {
+		return values, nil
+	}
+	values := new(int64Values)
+	var err error
 	optype := detectType(cmprule.ruleOp)
 	switch optype {
 	case valueSingle:
-		cmprule.int64Single, err = f(cmprule.ruleVal)
-		return
+		values.single, err = f(cmprule.ruleVal)
+		if err != nil {
+			return nil, cmprule.valueError(cmprule.ruleVal, "invalid value", err)
+		}
+	case valueRange:
+		values.min, err = f(cmprule.numMinStr)
+		if err != nil {
+			return nil, cmprule.valueError(cmprule.numMinStr, "invalid range value", err)
+		}
+		values.max, err = f(cmprule.numMaxStr)
+		if err != nil {
+			return nil, cmprule.valueError(cmprule.numMaxStr, "invalid range value", err)
+		}
+		if values.max < values.min {
+			return nil, cmprule.valueError(cmprule.ruleVal, "invalid range value, max value is smaller than min value", nil)
+		}
+	case valueList:
+		values.list = []int64{}
+		for _, str := range cmprule.numListStr {
+			v, err := f(str)
+			if err != nil {
+				return nil, cmprule.valueError(str, "invalid list value", err)
+			}
+			values.list = append(values.list, v)
+		}
+	default:
+		return nil, cmprule.opError("int64")
+	}
+	if cmprule.preparedInt64 == nil {
+		cmprule.preparedInt64 = make(map[int]*int64Values)
+	}
+	cmprule.preparedInt64[prepareType] = values
+	return values, nil
+}
+
+// prepareUint64 returns rule values parsed by f for prepareType, like prepareInt64
+func (cmprule *CMPRule) prepareUint64(prepareType int, f func(string) (uint64, error)) (*uint64Values, error) {
+	cmprule.preparedLock.Lock()
+	defer cmprule.preparedLock.Unlock()
+	if values, ok := cmprule.preparedUint64[prepareType]; ok {
+		return values, nil
+	}
+	values := new(uint64Values)
+	var err error
+	switch detectType(cmprule.ruleOp) {
+	case valueSingle:
+		values.single, err = f(cmprule.ruleVal)
+		if err != nil {
+			return nil, cmprule.valueError(cmprule.ruleVal, fmt.Sprintf("can't parse %v into uint64", cmprule.ruleVal), err)
+		}
 	case valueRange:
-		cmprule.int64Min, err = f(cmprule.numMinStr)
+		values.min, err = f(cmprule.numMinStr)
 		if err != nil {
-			return
+			return nil, cmprule.valueError(cmprule.numMinStr, "invalid range value", err)
 		}
-		cmprule.int64Max, err = f(cmprule.numMaxStr)
+		values.max, err = f(cmprule.numMaxStr)
 		if err != nil {
-			return
+			return nil, cmprule.valueError(cmprule.numMaxStr, "invalid range value", err)
 		}
-		if cmprule.int64Max < cmprule.int64Min {
-			err = fmt.Errorf("invalid range value, max value is smaller than min value")
+		if values.max < values.min {
+			return nil, cmprule.valueError(cmprule.ruleVal, "invalid range value, max value is smaller than min value", nil)
 		}
-		return
 	case valueList:
-		cmprule.int64List = []int64{}
+		values.list = []uint64{}
 		for _, str := range cmprule.numListStr {
-			var v int64
-			v, err = f(str)
+			v, err := f(str)
 			if err != nil {
-				return
+				return nil, cmprule.valueError(str, "invalid list value", err)
 			}
-			cmprule.int64List = append(cmprule.int64List, v)
+			values.list = append(values.list, v)
 		}
-		return
 	default:
-		return fmt.Errorf("invalid op for int64,%v", cmprule.ruleOp)
+		return nil, cmprule.opError("uint64")
 	}
+	if cmprule.preparedUint64 == nil {
+		cmprule.preparedUint64 = make(map[int]*uint64Values)
+	}
+	cmprule.preparedUint64[prepareType] = values
+	return values, nil
+}
+
+// prepareFloat64 returns rule values parsed by f for prepareType, like prepareInt64
+func (cmprule *CMPRule) prepareFloat64(prepareType int, f func(string) (float64, error)) (*float64Values, error) {
+	cmprule.preparedLock.Lock()
+	defer cmprule.preparedLock.Unlock()
+	if values, ok := cmprule.preparedFloat64[prepareType]; ok {
+		return values, nil
+	}
+	values := new(float64Values)
+	var err error
+	switch detectType(cmprule.ruleOp) {
+	case valueSingle:
+		values.single, err = f(cmprule.ruleVal)
+		if err != nil {
+			return nil, cmprule.valueError(cmprule.ruleVal, fmt.Sprintf("can't parse %v into float64", cmprule.ruleVal), err)
+		}
+	case valueRange:
+		values.min, err = f(cmprule.numMinStr)
+		if err != nil {
+			return nil, cmprule.valueError(cmprule.numMinStr, "invalid range value", err)
+		}
+		values.max, err = f(cmprule.numMaxStr)
+		if err != nil {
+			return nil, cmprule.valueError(cmprule.numMaxStr, "invalid range value", err)
+		}
+		if values.max < values.min {
+			return nil, cmprule.valueError(cmprule.ruleVal, "invalid range value, max value is smaller than min value", nil)
+		}
+	case valueList:
+		values.list = []float64{}
+		for _, str := range cmprule.numListStr {
+			v, err := f(str)
+			if err != nil {
+				return nil, cmprule.valueError(str, "invalid list value", err)
+			}
+			values.list = append(values.list, v)
+		}
+	default:
+		return nil, cmprule.opError("float64")
+	}
+	if cmprule.preparedFloat64 == nil {
+		cmprule.preparedFloat64 = make(map[int]*float64Values)
+	}
+	cmprule.preparedFloat64[prepareType] = values
+	return values, nil
 }
 
 func (cmprule *CMPRule) compareElement(element interface{}) (bool, error) {
+	if isApproxOp(cmprule.ruleOp) {
+		return cmprule.compareApprox(element)
+	}
 	etype := reflect.TypeOf(element)
 	fieldVal := reflect.ValueOf(element)
 	switch etype.String() {
 	case "int", "int8", "int16", "int32", "int64":
-		if cmprule.prepareInt64Type != prepareTypeNum {
-			err := cmprule.prepareInt64(cmprule.parseNumInt64Func)
-			if err != nil {
-				return false, err
-			}
-			cmprule.prepareInt64Type = prepareTypeNum
+		values, err := cmprule.prepareInt64(prepareTypeNum, cmprule.parseNumInt64Func)
+		if err != nil {
+			return false, err
 		}
-		return cmprule.compareNumberic(fieldVal.Int())
+		return cmprule.compareNumberic(fieldVal.Int(), values)
 	case "uint", "uint8", "uint16", "uint32", "uint64":
-		return cmprule.compareNumberic(fieldVal.Uint())
+		values, err := cmprule.prepareUint64(prepareTypeNum, cmprule.parseNumUint64Func)
+		if err != nil {
+			return false, err
+		}
+		return cmprule.compareNumberic(fieldVal.Uint(), values)
 	case "float32", "float64":
-		return cmprule.compareNumberic(fieldVal.Float())
+		values, err := cmprule.prepareFloat64(prepareTypeNum, cmprule.parseNumFloat64Func)
+		if err != nil {
+			return false, err
+		}
+		return cmprule.compareNumberic(fieldVal.Float(), values)
+	case "json.Number":
+		//an integer is compared exactly if the values could be parsed as integers, otherwise it is compared as float64
+		if i, err := strconv.ParseInt(string(element.(json.Number)), 10, 64); err == nil {
+			if values, err := cmprule.prepareInt64(prepareTypeNum, cmprule.parseNumInt64Func); err == nil {
+				return cmprule.compareNumberic(i, values)
+			}
+		} else if u, err := strconv.ParseUint(string(element.(json.Number)), 10, 64); err == nil {
+			if values, err := cmprule.prepareUint64(prepareTypeNum, cmprule.parseNumUint64Func); err == nil {
+				return cmprule.compareNumberic(u, values)
+			}
+		}
+		f, err := element.(json.Number).Float64()
+		if err != nil {
+			return false, fmt.Errorf("field %v has invalid json number %v", cmprule.ruleFieldName, element)
+		}
+		values, err := cmprule.prepareFloat64(prepareTypeNum, cmprule.parseNumFloat64Func)
+		if err != nil {
+			return false, err
+		}
+		return cmprule.compareNumberic(f, values)
 	case "string":
 		return cmprule.compareString(fieldVal.String())
+	case "bool":
+		return cmprule.compareBool(fieldVal.Bool())
 	case "time.Duration":
-		if cmprule.prepareInt64Type != prepareTypeDuration {
-			err := cmprule.prepareInt64(cmprule.parseDurationInt64Func)
-			if err != nil {
-				return false, err
-			}
-			cmprule.prepareInt64Type = prepareTypeDuration
+		values, err := cmprule.prepareInt64(prepareTypeDuration, cmprule.parseDurationInt64Func)
+		if err != nil {
+			return false, err
 		}
-		return cmprule.compareNumberic(fieldVal.Interface().(time.Duration).Nanoseconds())
+		return cmprule.compareNumberic(fieldVal.Interface().(time.Duration).Nanoseconds(), values)
 	case "time.Time":
-		if cmprule.prepareInt64Type != prepareTypeTimestamp {
-			err := cmprule.prepareInt64(cmprule.parseTimeInt64Func)
-			if err != nil {
-				return false, err
-			}
-			cmprule.prepareInt64Type = prepareTypeTimestamp
+		values, err := cmprule.prepareTime()
+		if err != nil {
+			return false, err
 		}
-		return cmprule.compareNumberic(fieldVal.Interface().(time.Time).Unix())
-	case "net.IP":
-		return cmprule.compareIP(fieldVal.Interface().(net.IP))
+		return cmprule.compareTime(fieldVal.Interface().(time.Time), values)
+	case "net.IP", "net.IPNet", "netip.Addr", "netip.Prefix":
+		return cmprule.compareIP(etype.String(), ipField(element))
 	default:
-		return false, fmt.Errorf("field %v has unsupported type %v", cmprule.ruleFieldName, etype.String())
+		return false, cmprule.typeError(etype)
 	}
 }
 
-// Compare to input, which must be a struct, based on parsed rules
+// Compare to input, which must be a struct, or a map/slice like a decoded JSON document, based on parsed rules
 // return true/false if comparison is done successfully
 // return a non-nil error if fail to do the comparison
+// if the field name contains wildcard, every element is compared and the results are combined by the quantifier;
+// if the value is a field reference like "$RxPkts", the referred field is resolved in input
 func (cmprule *CMPRule) Compare(input interface{}) (bool, error) {
-	fieldInt, err := getStructField(input, cmprule.fieldNameList)
+	compare, _, err := cmprule.elementComparer(input)
 	if err != nil {
-		return false, err
+		return false, cmprule.annotateError(err)
 	}
-	return cmprule.compareElement(fieldInt)
+	r, err := cmprule.walkRuleField(input, func(name string, v interface{}) (bool, error) {
+		return compare(v)
+	}, func(FieldValue) {})
+	return r, cmprule.annotateError(err)
 }
 
-func (cmprule *CMPRule) compareIP(inputip net.IP) (bool, error) {
-	for _, prefix := range cmprule.ipNetList {
-		if prefix.Contains(inputip) {
-			return true, nil
+func (cmprule *CMPRule) compareBool(input bool) (bool, error) {
+	switch detectType(cmprule.ruleOp) {
+	case valueSingle:
+		v, err := cmprule.parseBoolFunc(cmprule.ruleVal)
+		if err != nil {
+			return false, cmprule.valueError(cmprule.ruleVal, fmt.Sprintf("can't parse %v into bool", cmprule.ruleVal), err)
+		}
+		switch cmprule.ruleOp {
+		case opNumEq:
+			return input == v, nil
+		case opNumNotEq:
+			return input != v, nil
+		}
+	case valueList:
+		found := false
+		for _, s := range cmprule.numListStr {
+			v, err := cmprule.parseBoolFunc(s)
+			if err != nil {
+				return false, cmprule.valueError(s, fmt.Sprintf("can't parse %v into bool", s), err)
+			}
+			if input == v {
+				found = true
+				break
+			}
 		}
+		return found == (cmprule.ruleOp == opNumIs), nil
 	}
-	return false, nil
+	return false, cmprule.opError("bool")
 }
 
 func (cmprule *CMPRule) compareString(input string) (bool, error) {
@@ -475,55 +1003,146 @@ func (cmprule *CMPRule) compareString(input string) (bool, error) {
 			}
 		}
 		return !found, nil
+	case opStrPrefix:
+		return anyString(cmprule.strList, func(val string) bool { return strings.HasPrefix(input, val) }), nil
+	case opStrSuffix:
+		return anyString(cmprule.strList, func(val string) bool { return strings.HasSuffix(input, val) }), nil
+	case opStrSameI:
+		return anyString(cmprule.strList, func(val string) bool { return strings.EqualFold(input, val) }), nil
+	case opStrDifferI:
+		return !anyString(cmprule.strList, func(val string) bool { return strings.EqualFold(input, val) }), nil
+	case opStrContainI:
+		lower := strings.ToLower(input)
+		return anyString(cmprule.strList, func(val string) bool { return strings.Contains(lower, strings.ToLower(val)) }), nil
+	case opStrNotContainI:
+		lower := strings.ToLower(input)
+		return !anyString(cmprule.strList, func(val string) bool { return strings.Contains(lower, strings.ToLower(val)) }), nil
+	case opStrPrefixI:
+		lower := strings.ToLower(input)
+		return anyString(cmprule.strList, func(val string) bool { return strings.HasPrefix(lower, strings.ToLower(val)) }), nil
+	case opStrSuffixI:
+		lower := strings.ToLower(input)
+		return anyString(cmprule.strList, func(val string) bool { return strings.HasSuffix(lower, strings.ToLower(val)) }), nil
+	case opStrMatch, opStrMatchI:
+		return cmprule.matchRegexp(input), nil
+	case opStrNotMatch, opStrNotMatchI:
+		return !cmprule.matchRegexp(input), nil
+	case opStrLike:
+		return anyString(cmprule.strList, func(val string) bool { return globMatch(val, input) }), nil
+	case opStrNotLike:
+		return !anyString(cmprule.strList, func(val string) bool { return globMatch(val, input) }), nil
 	default:
-		return false, fmt.Errorf("invalid op %v for string", cmprule.ruleOp)
+		return false, cmprule.opError("string")
+	}
+
+}
+
+// anyString returns true if f returns true for any string in list
+func anyString(list []string, f func(string) bool) bool {
+	for _, val := range list {
+		if f(val) {
+			return true
+		}
+	}
+	return false
+}
+
+// compileRegexpList compiles each string in strList as a regexp,
+// for case-insensitive operators, the regexp is compiled with flag i
+func (cmprule *CMPRule) compileRegexpList() error {
+	cmprule.regexpList = nil
+	for _, val := range cmprule.strList {
+		expr := val
+		if cmprule.ruleOp == opStrMatchI || cmprule.ruleOp == opStrNotMatchI {
+			expr = "(?i)" + val
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return cmprule.valueError(val, fmt.Sprintf("invalid regexp %v", val), err)
+		}
+		cmprule.regexpList = append(cmprule.regexpList, re)
 	}
+	return nil
+}
 
+// checkGlobList returns an error if any pattern in strList is malformed
+func (cmprule *CMPRule) checkGlobList() error {
+	for _, pattern := range cmprule.strList {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return cmprule.valueError(pattern, fmt.Sprintf("invalid pattern %v", pattern), err)
+		}
+	}
+	return nil
 }
 
-// ClearPreparedInt64Value Clear the previous pre-parsed int64 values, this is only needed when compare a new type of struct with a already parsed rule
-// e.g. this is not needed, if you use same rule to compare different instances of same type of struct
+// globMatch returns true if input matches shell pattern, see path.Match for pattern syntax;
+// pattern is already checked by checkGlobList
+func globMatch(pattern, input string) bool {
+	matched, _ := path.Match(pattern, input)
+	return matched
+}
+
+// matchRegexp returns true if input matches any regexp in regexpList
+func (cmprule *CMPRule) matchRegexp(input string) bool {
+	for _, re := range cmprule.regexpList {
+		if re.MatchString(input) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClearPreparedInt64Value Clear the previous pre-parsed int64, uint64 and float64 values, this is only needed when a parse function is changed after the rule is parsed;
+// pre-parsed values are cached for each type, so it is not needed to compare different types of struct with a already parsed rule
 func (cmprule *CMPRule) ClearPreparedInt64Value() {
-	cmprule.prepareInt64Type = prepareTypeNotPrepared
+	cmprule.preparedLock.Lock()
+	cmprule.preparedInt64 = nil
+	cmprule.preparedUint64 = nil
+	cmprule.preparedFloat64 = nil
+	cmprule.preparedTime = nil
+	cmprule.preparedIP = nil
+	cmprule.fieldUnits = nil
+	cmprule.preparedLock.Unlock()
 }
 
-//input could only be int64,uint64 or float64
-func (cmprule *CMPRule) compareNumberic(input interface{}) (bool, error) {
+//input could only be int64,uint64 or float64, prepared is the pre-parsed values of same type: *int64Values, *uint64Values or *float64Values
+func (cmprule *CMPRule) compareNumberic(input interface{}, prepared interface{}) (bool, error) {
 	inputKind := reflect.TypeOf(input).Kind()
 	switch inputKind {
 	case reflect.Int64:
 		inputval := input.(int64)
+		values := prepared.(*int64Values)
 		vtype := detectType(cmprule.ruleOp)
 		switch vtype {
 		case valueSingle:
 			switch cmprule.ruleOp {
 			case "==":
-				return cmprule.int64Single == inputval, nil
+				return values.single == inputval, nil
 			case "!=":
-				return cmprule.int64Single != inputval, nil
+				return values.single != inputval, nil
 			case ">=":
-				return inputval >= cmprule.int64Single, nil
+				return inputval >= values.single, nil
 			case "<=":
-				return inputval <= cmprule.int64Single, nil
+				return inputval <= values.single, nil
 			case ">":
-				return inputval > cmprule.int64Single, nil
+				return inputval > values.single, nil
 			case "<":
-				return inputval < cmprule.int64Single, nil
+				return inputval < values.single, nil
 			default:
-				return false, fmt.Errorf("invalid op %v for type %v value %v", cmprule.ruleOp, inputKind, cmprule.ruleVal)
+				return false, cmprule.opError(inputKind.String())
 			}
 		case valueRange:
 			switch cmprule.ruleOp {
 			case "in":
-				return inputval >= cmprule.int64Min && inputval <= cmprule.int64Max, nil
+				return inputval >= values.min && inputval <= values.max, nil
 			case "notin":
-				return !(inputval >= cmprule.int64Min && inputval <= cmprule.int64Max), nil
+				return !(inputval >= values.min && inputval <= values.max), nil
 			default:
-				return false, fmt.Errorf("invalid op %v for type %v value %v", cmprule.ruleOp, inputKind, cmprule.ruleVal)
+				return false, cmprule.opError(inputKind.String())
 			}
 		case valueList:
 			found := false
-			for _, v := range cmprule.int64List {
+			for _, v := range values.list {
 				if inputval == v {
 					found = true
 					break
@@ -535,67 +1154,40 @@ func (cmprule *CMPRule) compareNumberic(input interface{}) (bool, error) {
 			case "not":
 				return !found, nil
 			default:
-				return false, fmt.Errorf("invalid op %v for type %v, value %v", cmprule.ruleOp, inputKind, cmprule.ruleVal)
+				return false, cmprule.opError(inputKind.String())
 			}
 		default:
-			return false, fmt.Errorf("invalid op and/or value: %v %v", cmprule.ruleOp, cmprule.ruleVal)
+			return false, cmprule.opError(inputKind.String())
 		}
 	case reflect.Uint64:
 		inputval := input.(uint64)
-		vtype := detectType(cmprule.ruleOp)
-		switch vtype {
+		values := prepared.(*uint64Values)
+		switch detectType(cmprule.ruleOp) {
 		case valueSingle:
-			singleVal, err := strconv.ParseUint(cmprule.ruleVal, 0, 64)
-			if err != nil {
-				return false, fmt.Errorf("can't parse %v into int", cmprule.ruleVal)
-			}
 			switch cmprule.ruleOp {
 			case "==":
-				return singleVal == inputval, nil
+				return values.single == inputval, nil
 			case "!=":
-				return singleVal != inputval, nil
+				return values.single != inputval, nil
 			case ">=":
-				return inputval >= singleVal, nil
+				return inputval >= values.single, nil
 			case "<=":
-				return inputval <= singleVal, nil
+				return inputval <= values.single, nil
 			case ">":
-				return inputval > singleVal, nil
+				return inputval > values.single, nil
 			case "<":
-				return inputval < singleVal, nil
-			default:
-				return false, fmt.Errorf("invalid op %v for type %v value %v", cmprule.ruleOp, inputKind, cmprule.ruleVal)
+				return inputval < values.single, nil
 			}
 		case valueRange:
-			min, err := strconv.ParseUint(cmprule.numMinStr, 0, 64)
-			if err != nil {
-				return false, fmt.Errorf("invalid range value %v", cmprule.numMinStr)
-			}
-			max, err := strconv.ParseUint(cmprule.numMaxStr, 0, 64)
-			if err != nil {
-				return false, fmt.Errorf("invalid range value %v", cmprule.numMaxStr)
-			}
-			if min > max {
-				return false, fmt.Errorf("invalid range value, min>max %v", cmprule.ruleVal)
-			}
 			switch cmprule.ruleOp {
 			case "in":
-				return inputval >= min && inputval <= max, nil
+				return inputval >= values.min && inputval <= values.max, nil
 			case "notin":
-				return !(inputval >= min && inputval <= max), nil
-			default:
-				return false, fmt.Errorf("invalid op %v for type %v value %v", cmprule.ruleOp, inputKind, cmprule.ruleVal)
+				return !(inputval >= values.min && inputval <= values.max), nil
 			}
 		case valueList:
-			var vallist []uint64
-			for _, s := range cmprule.numListStr {
-				v, err := strconv.ParseUint(s, 0, 64)
-				if err != nil {
-					return false, fmt.Errorf("%v is not a valid int value", s)
-				}
-				vallist = append(vallist, v)
-			}
 			found := false
-			for _, v := range vallist {
+			for _, v := range values.list {
 				if inputval == v {
 					found = true
 					break
@@ -606,70 +1198,39 @@ func (cmprule *CMPRule) compareNumberic(input interface{}) (bool, error) {
 				return found, nil
 			case "not":
 				return !found, nil
-			default:
-				return false, fmt.Errorf("invalid op %v for type %v, value %v", cmprule.ruleOp, inputKind, cmprule.ruleVal)
 			}
-		default:
-			return false, fmt.Errorf("invalid op and/or value: %v %v", cmprule.ruleOp, cmprule.ruleVal)
-
 		}
+		return false, cmprule.opError(inputKind.String())
 	case reflect.Float64:
 		inputval := input.(float64)
-		vtype := detectType(cmprule.ruleOp)
-		switch vtype {
+		values := prepared.(*float64Values)
+		switch detectType(cmprule.ruleOp) {
 		case valueSingle:
-			singleVal, err := strconv.ParseFloat(cmprule.ruleVal, 64)
-			if err != nil {
-				return false, fmt.Errorf("can't parse %v into int", cmprule.ruleVal)
-			}
 			switch cmprule.ruleOp {
 			case "==":
-				return singleVal == inputval, nil
+				return floatEqual(values.single, inputval), nil
 			case "!=":
-				return singleVal != inputval, nil
+				return !floatEqual(values.single, inputval), nil
 			case ">=":
-				return inputval >= singleVal, nil
+				return inputval >= values.single, nil
 			case "<=":
-				return inputval <= singleVal, nil
+				return inputval <= values.single, nil
 			case ">":
-				return inputval > singleVal, nil
+				return inputval > values.single, nil
 			case "<":
-				return inputval < singleVal, nil
-			default:
-				return false, fmt.Errorf("invalid op %v for type %v value %v", cmprule.ruleOp, inputKind, cmprule.ruleVal)
+				return inputval < values.single, nil
 			}
 		case valueRange:
-			min, err := strconv.ParseFloat(cmprule.numMinStr, 64)
-			if err != nil {
-				return false, fmt.Errorf("invalid range value %v", cmprule.numMinStr)
-			}
-			max, err := strconv.ParseFloat(cmprule.numMaxStr, 64)
-			if err != nil {
-				return false, fmt.Errorf("invalid range value %v", cmprule.numMaxStr)
-			}
-			if min > max {
-				return false, fmt.Errorf("invalid range value, min>max %v", cmprule.ruleVal)
-			}
 			switch cmprule.ruleOp {
 			case "in":
-				return inputval >= min && inputval <= max, nil
+				return inputval >= values.min && inputval <= values.max, nil
 			case "notin":
-				return !(inputval >= min && inputval <= max), nil
-			default:
-				return false, fmt.Errorf("invalid op %v for type %v value %v", cmprule.ruleOp, inputKind, cmprule.ruleVal)
+				return !(inputval >= values.min && inputval <= values.max), nil
 			}
 		case valueList:
-			var vallist []float64
-			for _, s := range cmprule.strList {
-				v, err := strconv.ParseFloat(s, 64)
-				if err != nil {
-					return false, fmt.Errorf("%v is not a valid int value", s)
-				}
-				vallist = append(vallist, v)
-			}
 			found := false
-			for _, v := range vallist {
-				if inputval == v {
+			for _, v := range values.list {
+				if floatEqual(inputval, v) {
 					found = true
 					break
 				}
@@ -679,15 +1240,11 @@ func (cmprule *CMPRule) compareNumberic(input interface{}) (bool, error) {
 				return found, nil
 			case "not":
 				return !found, nil
-			default:
-				return false, fmt.Errorf("invalid op %v for type %v, value %v", cmprule.ruleOp, inputKind, cmprule.ruleVal)
 			}
-		default:
-			return false, fmt.Errorf("invalid op and/or value: %v %v", cmprule.ruleOp, cmprule.ruleVal)
-
 		}
+		return false, cmprule.opError(inputKind.String())
 	default:
-		return false, fmt.Errorf("unsupported type:%v", inputKind)
+		return false, cmprule.typeError(reflect.TypeOf(input))
 	}
 }
 
@@ -726,6 +1283,13 @@ func (cmprule *CMPRule) SetParseStrListFunc(f func(listval string) ([]string, er
 	cmprule.parseStrListFunc = f
 }
 
+// SetParseGlobListFunc set f as function to parse a string that represents a list of shell patterns into a slice of string.
+// this is used only by type string with operator like and notlike.
+// default function is same as the one of SetParseStrListFunc
+func (cmprule *CMPRule) SetParseGlobListFunc(f func(listval string) ([]string, error)) {
+	cmprule.parseGlobListFunc = f
+}
+
 // SetParseNumInt64Func set f as function to parse a string that represents a number into int64
 // this is used by type int,int8,int16,int32,int64.
 // default function uses strconv.ParseInt(numstr, 0, 64).
@@ -733,6 +1297,20 @@ func (cmprule *CMPRule) SetParseNumInt64Func(f func(numstr string) (int64, error
 	cmprule.parseNumInt64Func = f
 }
 
+// SetParseUint64Func set f as function to parse a string that represents a number into uint64
+// this is used by type uint,uint8,uint16,uint32,uint64.
+// default function uses strconv.ParseUint(numstr, 0, 64).
+func (cmprule *CMPRule) SetParseUint64Func(f func(numstr string) (uint64, error)) {
+	cmprule.parseNumUint64Func = f
+}
+
+// SetParseFloat64Func set f as function to parse a string that represents a number into float64
+// this is used by type float32,float64,json.Number, and the value and tolerance of approximate compare operators.
+// default function uses strconv.ParseFloat(numstr, 64).
+func (cmprule *CMPRule) SetParseFloat64Func(f func(numstr string) (float64, error)) {
+	cmprule.parseNumFloat64Func = f
+}
+
 // SetparseDurationInt64Func set f as function to parse a string that represents time.Duration into int64.
 // this is used only by type time.Duration.
 // default function uses time.ParseDuration
@@ -740,11 +1318,17 @@ func (cmprule *CMPRule) SetparseDurationInt64Func(f func(durationstr string) (in
 	cmprule.parseDurationInt64Func = f
 }
 
-// SetparseTimeInt64Func set f as function to parse a string that represents time.Time into int64.
-// this is used only by type time.Time.
-// default function uses time.Parse, with format string as const TIMEFMTSTR
+// SetparseTimeInt64Func set f as function to parse a string that represents time.Time into Unix time in seconds.
+// this is used only by type time.Time, it replaces the function set by SetParseTimeFunc.
+// use SetParseTimeFunc instead to keep sub-second precision
 func (cmprule *CMPRule) SetparseTimeInt64Func(f func(timestr string) (int64, error)) {
-	cmprule.parseTimeInt64Func = f
+	cmprule.parseTimeFunc = func(timestr string) (time.Time, error) {
+		sec, err := f(timestr)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(sec, 0), nil
+	}
 }
 
 // SetParseFieldNameFunc set f as function to parse field_name string into a list field name,
@@ -753,3 +1337,18 @@ func (cmprule *CMPRule) SetparseTimeInt64Func(f func(timestr string) (int64, err
 func (cmprule *CMPRule) SetParseFieldNameFunc(f func(field_name string) []string) {
 	cmprule.parseFieldNamFunc = f
 }
+
+// SetFieldNaming set how field name in the rule is matched to a struct field,
+// if ignoreCase is true, the name is matched case-insensitively.
+// default is NamingTag and case-sensitive
+func (cmprule *CMPRule) SetFieldNaming(naming FieldNaming, ignoreCase bool) {
+	cmprule.resolver.naming = naming
+	cmprule.resolver.ignoreCase = ignoreCase
+}
+
+// SetParseBoolFunc set f as function to parse a string that represents a bool,
+// this is used only by type bool.
+// default function is strconv.ParseBool
+func (cmprule *CMPRule) SetParseBoolFunc(f func(boolstr string) (bool, error)) {
+	cmprule.parseBoolFunc = f
+}
@@ -2,7 +2,12 @@
 package cmprule
 
 import (
+	"errors"
+	"fmt"
 	"net"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -31,10 +36,12 @@ type testStruct struct {
 	IP2       net.IP
 	PointNum1 *int
 	PointNum2 *int
+	Bool1     bool
+	PointBool *bool
 }
 
 var test_struct testStruct = testStruct{
-	Num1: -120, Num_uint1: 120, Float1: 12.5, Str1: "test1", Str2: `"inside"outside`,
+	Num1: -120, Num_uint1: 120, Float1: 12.5, Str1: "test1", Str2: `"inside"outside`, Bool1: true,
 	Duration1: 10 * time.Second,
 	IP1:       net.ParseIP("1.1.1.1"),
 	IP2:       net.ParseIP("2001:dead::1"),
@@ -101,6 +108,35 @@ var test_list_lv1 = []testResult{
 	{`Str2:contain:"\"inside\"" "test2" ""`, true, false},
 	{`Str1:same:test1 "test2"`, false, false},
 	{`Str1:same:test1 `, false, true},
+	{`Str1:prefix:"abc" "tes"`, true, false},
+	{`Str1:prefix:"est"`, false, false},
+	{`Str1:suffix:"t1"`, true, false},
+	{`Str1:suffix:"t2" "test"`, false, false},
+	{`Str1:isame:"TEST1"`, true, false},
+	{`Str1:idiffer:"TEST1"`, false, false},
+	{`Str1:idiffer:"TEST2"`, true, false},
+	{`Str1:icontain:"EST"`, true, false},
+	{`Str1:inotcontain:"EST"`, false, false},
+	{`Str1:iprefix:"TE"`, true, false},
+	{`Str1:isuffix:"T1"`, true, false},
+	{`Str1:match:"^test\d$"`, true, false},
+	{`Str1:match:"^TEST\d$"`, false, false},
+	{`Str1:match:"^x" "s.1"`, true, false},
+	{`Str1:notmatch:"^x" "s.1"`, false, false},
+	{`Str1:notmatch:"\d\d"`, true, false},
+	{`Str1:imatch:"^TEST\d$"`, true, false},
+	{`Str1:inotmatch:"^TEST\d$"`, false, false},
+	{`Str2:match:"^\"inside\""`, true, false},
+	{`Str1:match:"(a"`, false, true},
+	{`Num1:match:"1"`, false, true},
+	{`Str1:like:"te*"`, true, false},
+	{`Str1:like:"test?"`, true, false},
+	{`Str1:like:"test??"`, false, false},
+	{`Str1:like:"x*" "*[0-9]"`, true, false},
+	{`Str1:like:"test[^1]"`, false, false},
+	{`Str1:notlike:"x*" "y*"`, true, false},
+	{`Str1:notlike:"*1"`, false, false},
+	{`Str1:like:"[a"`, false, true},
 	//duration
 	{"Duration1:==:0m10s", true, false},
 	{"Duration1:==:10s", true, false},
@@ -124,8 +160,21 @@ var test_list_lv1 = []testResult{
 	{"IP1:within:1.1.1.0/32 2.2.2.2/32", false, false},
 	{"IP1:within:1.1.1.99/24 2.2.2.2/32", true, false},
 	{"IP2:within:2001:dead::99/64 2002:beef::/128", true, false},
-	{"IP2:notwithin:2002:dead::23/64 2002:beef::/128", false, false},
+	{"IP2:notwithin:2002:dead::23/64 2002:beef::/128", true, false},
 	{"IP1:within:1.1.1.1/32 2001:dead::1/32", true, false},
+	//bool
+	{"Bool1:==:true", true, false},
+	{"Bool1:==:1", true, false},
+	{"Bool1:!=:true", false, false},
+	{"Bool1:==:F", false, false},
+	{"Bool1:is:false true", true, false},
+	{"Bool1:not:false", true, false},
+	{"Bool1:not:true", false, false},
+	{"Bool1:==:yes", false, true},
+	{"Bool1:is:false yes", false, true},
+	{"Bool1:>:false", false, true},
+	{"Bool1:in:false true", false, true},
+	{"PointBool:==:true", false, true},
 	//pointer
 	{"PointNum1:==:99", true, false},
 	{"PointNum1:<:99", false, false},
@@ -164,7 +213,12 @@ var test_list_lv3 = []testResult{
 }
 
 func tableTest(input interface{}, expectedResults []testResult, t *testing.T) {
-	cmp := NewDefaultCMPRule()
+	tableTestWithFunc(input, expectedResults, NewDefaultCMPRule, t)
+}
+
+// tableTestWithFunc is same as tableTest, except the rules are parsed by a CMPRule instance created by newRule
+func tableTestWithFunc(input interface{}, expectedResults []testResult, newRule func() *CMPRule, t *testing.T) {
+	cmp := newRule()
 	var result bool
 	var err error
 	for _, tt := range expectedResults {
@@ -185,8 +239,11 @@ func tableTest(input interface{}, expectedResults []testResult, t *testing.T) {
 					t.Logf("input: %v, expected err: %v", tt.in, err)
 				}
 			} else {
+				if tt.expect_err {
+					t.Fatalf("input: %v, expect error but succeed", tt.in)
+				}
 				if tt.out_bool != result {
-					t.Fatal(err)
+					t.Fatalf("input: %v, expect %v, got %v", tt.in, tt.out_bool, result)
 				}
 			}
 		}
@@ -208,3 +265,163 @@ func TestCmpRule(t *testing.T) {
 	tableTest(test_structlv2, test_list_lv2, t)
 	tableTest(test_structlv3, test_list_lv3, t)
 }
+
+func TestParseBoolFunc(t *testing.T) {
+	b := true
+	input := testStruct{Bool1: false, PointBool: &b}
+	cmp := NewDefaultCMPRule()
+	cmp.SetParseBoolFunc(func(boolstr string) (bool, error) {
+		switch strings.ToLower(boolstr) {
+		case "yes", "on":
+			return true, nil
+		case "no", "off":
+			return false, nil
+		}
+		return false, fmt.Errorf("invalid bool %v", boolstr)
+	})
+	for _, tt := range []testResult{
+		{"Bool1:==:no", true, false},
+		{"Bool1:==:on", false, false},
+		{"PointBool:is:off yes", true, false},
+		{"PointBool:==:true", false, true},
+	} {
+		if err := cmp.ParseRule(tt.in); err != nil {
+			t.Fatal(err)
+		}
+		result, err := cmp.Compare(input)
+		t.Logf("input: %v; result: %v, err: %v", tt.in, result, err)
+		if (err != nil) != tt.expect_err {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if result != tt.out_bool {
+			t.Fatalf("expect %v, got %v", tt.out_bool, result)
+		}
+	}
+}
+
+func TestParseBoolFuncError(t *testing.T) {
+	errInvalid := errors.New("invalid bool")
+	b := true
+	input := testStruct{Bool1: false, PointBool: &b}
+	cases := []struct {
+		rule  string
+		input interface{}
+	}{
+		{"Bool1:==:maybe", input},
+		{"PointBool:is:maybe true", input},
+		{"Addr:is4:maybe", testStructIP{Addr: net.ParseIP("10.0.0.1")}},
+	}
+	for _, c := range cases {
+		cmp := NewDefaultCMPRule()
+		cmp.SetParseBoolFunc(func(boolstr string) (bool, error) {
+			if boolstr == "maybe" {
+				return false, errInvalid
+			}
+			return strconv.ParseBool(boolstr)
+		})
+		if err := cmp.ParseRule(c.rule); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := cmp.Compare(c.input); !errors.Is(err, errInvalid) {
+			t.Fatalf("rule %v: expect error wraps %v, got %v", c.rule, errInvalid, err)
+		}
+		if err := cmp.Validate(reflect.TypeOf(c.input)); !errors.Is(err, errInvalid) {
+			t.Fatalf("rule %v: expect validate error wraps %v, got %v", c.rule, errInvalid, err)
+		}
+	}
+}
+
+func TestParseGlobListFunc(t *testing.T) {
+	input := testStruct{Str1: "core-1-rtr01"}
+	cmp := NewDefaultCMPRule()
+	cmp.SetParseGlobListFunc(func(listval string) ([]string, error) {
+		return strings.Split(listval, ","), nil
+	})
+	if err := cmp.ParseRule("Str1:like:edge-*,core-*-rtr??"); err != nil {
+		t.Fatal(err)
+	}
+	if r, err := cmp.Compare(input); err != nil || !r {
+		t.Fatalf("expect true, got %v, %v", r, err)
+	}
+	//other string operators are not affected
+	if err := cmp.ParseRule("Str1:same:core-1-rtr01"); err == nil {
+		t.Fatal("expect error")
+	}
+}
+
+// parseSuffixNum parses a number with an optional suffix k, M or G, like "1.5k"
+func parseSuffixNum(numstr string) (float64, error) {
+	mul := 1.0
+	switch {
+	case strings.HasSuffix(numstr, "k"):
+		mul = 1e3
+	case strings.HasSuffix(numstr, "M"):
+		mul = 1e6
+	case strings.HasSuffix(numstr, "G"):
+		mul = 1e9
+	}
+	if mul != 1 {
+		numstr = numstr[:len(numstr)-1]
+	}
+	f, err := strconv.ParseFloat(numstr, 64)
+	return f * mul, err
+}
+
+func TestParseNumFuncs(t *testing.T) {
+	input := testStruct{Num1: 1500, Num_uint1: 2000000, Float1: 1500}
+	calls := 0
+	cmp := NewDefaultCMPRule()
+	cmp.SetParseNumInt64Func(func(numstr string) (int64, error) {
+		f, err := parseSuffixNum(numstr)
+		return int64(f), err
+	})
+	cmp.SetParseUint64Func(func(numstr string) (uint64, error) {
+		calls++
+		f, err := parseSuffixNum(numstr)
+		return uint64(f), err
+	})
+	cmp.SetParseFloat64Func(parseSuffixNum)
+	for _, tt := range []testResult{
+		{"Num1:==:1.5k", true, false},
+		{"Num_uint1:==:2M", true, false},
+		{"Num_uint1:in:1M 3M", true, false},
+		{"Num_uint1:not:1M 2M", false, false},
+		{"Float1:is:1k 1.5k", true, false},
+		{"Float1:<:1k", false, false},
+		{"Float1:~=:1.5k ±1", true, false},
+		{"Num_uint1:>:2X", false, true},
+		{"Float1:in:2k 1k", false, true},
+	} {
+		if err := cmp.ParseRule(tt.in); err != nil {
+			t.Fatal(err)
+		}
+		result, err := cmp.Compare(input)
+		t.Logf("input: %v; result: %v, err: %v", tt.in, result, err)
+		if (err != nil) != tt.expect_err {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if result != tt.out_bool {
+			t.Fatalf("expect %v, got %v", tt.out_bool, result)
+		}
+	}
+	//values are parsed once and cached until cleared
+	if err := cmp.ParseRule("Num_uint1:>=:1M"); err != nil {
+		t.Fatal(err)
+	}
+	calls = 0
+	for i := 0; i < 3; i++ {
+		if r, err := cmp.Compare(input); err != nil || !r {
+			t.Fatalf("expect true, got %v, %v", r, err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("expect value parsed once, got %d", calls)
+	}
+	cmp.SetParseUint64Func(func(numstr string) (uint64, error) {
+		return strconv.ParseUint(numstr, 0, 64)
+	})
+	cmp.ClearPreparedInt64Value()
+	if _, err := cmp.Compare(input); err == nil {
+		t.Fatal("expect error after parse function is changed")
+	}
+}
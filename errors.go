@@ -0,0 +1,179 @@
+// Copyright 2020 Hu Jun. All rights reserved.
+// This project is licensed under the terms of the MIT license.
+// license that can be found in the LICENSE file.
+
+package cmprule
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ParseError is returned if a rule or expression, or a value in it, can't be parsed
+type ParseError struct {
+	// Rule is the raw rule or expression text
+	Rule string
+	// Token is the part of Rule which can't be parsed
+	Token string
+	// Column is the byte offset of Token in Rule, -1 if unknown
+	Column int
+	// Msg describes the error
+	Msg string
+	// Err is the underlying error, could be nil
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%v, %v", e.Msg, e.Err)
+	}
+	return e.Msg
+}
+
+// Unwrap returns the underlying error
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// FieldNotFoundError is returned if a field, map key or slice index in the rule doesn't exist in the input
+type FieldNotFoundError struct {
+	// Rule is the raw rule text
+	Rule string
+	// Token is the name of the field, map key or index
+	Token string
+	// Column is the byte offset of Token in Rule, -1 if unknown
+	Column int
+	// Type is the type of the struct, map or slice the field is looked up in
+	Type string
+	// Msg describes the error
+	Msg string
+}
+
+func (e *FieldNotFoundError) Error() string {
+	return e.Msg
+}
+
+// TypeMismatchError is returned if the type of a field in the input doesn't support the rule,
+// e.g. the field type is unsupported, or an index is applied to a field which is not a slice, array or map
+type TypeMismatchError struct {
+	// Rule is the raw rule text
+	Rule string
+	// Token is the field name in Rule
+	Token string
+	// Column is the byte offset of Token in Rule, -1 if unknown
+	Column int
+	// Type is the actual type of the field
+	Type string
+	// Msg describes the error
+	Msg string
+}
+
+func (e *TypeMismatchError) Error() string {
+	return e.Msg
+}
+
+// InvalidOperatorError is returned if the operator in the rule is unknown, or is invalid for the type of the field
+type InvalidOperatorError struct {
+	// Rule is the raw rule text
+	Rule string
+	// Token is the operator
+	Token string
+	// Column is the byte offset of Token in Rule, -1 if unknown
+	Column int
+	// Type is the type of the field, empty if the operator is unknown
+	Type string
+}
+
+func (e *InvalidOperatorError) Error() string {
+	if e.Type == "" {
+		return fmt.Sprintf("unknown op %v", e.Token)
+	}
+	return fmt.Sprintf("invalid op %v for %v", e.Token, e.Type)
+}
+
+// ruleError is implemented by typed errors of a rule
+type ruleError interface {
+	error
+	location() (rule, token string, column int)
+	setLocation(rule string, column int)
+}
+
+func (e *ParseError) location() (string, string, int) { return e.Rule, e.Token, e.Column }
+func (e *ParseError) setLocation(rule string, column int) {
+	e.Rule, e.Column = rule, column
+}
+
+func (e *FieldNotFoundError) location() (string, string, int) { return e.Rule, e.Token, e.Column }
+func (e *FieldNotFoundError) setLocation(rule string, column int) {
+	e.Rule, e.Column = rule, column
+}
+
+func (e *TypeMismatchError) location() (string, string, int) { return e.Rule, e.Token, e.Column }
+func (e *TypeMismatchError) setLocation(rule string, column int) {
+	e.Rule, e.Column = rule, column
+}
+
+func (e *InvalidOperatorError) location() (string, string, int) { return e.Rule, e.Token, e.Column }
+func (e *InvalidOperatorError) setLocation(rule string, column int) {
+	e.Rule, e.Column = rule, column
+}
+
+// annotateError sets the rule text and column of err, if it is a typed error of a rule without rule text,
+// like the ones returned by fieldResolver
+func (cmprule *CMPRule) annotateError(err error) error {
+	var re ruleError
+	if errors.As(err, &re) {
+		if rule, token, _ := re.location(); rule == "" {
+			re.setLocation(cmprule.rawRule, strings.Index(cmprule.rawRule, token))
+		}
+	}
+	return err
+}
+
+// valueError returns a ParseError for token in the value of the rule,
+// if err is already a typed error of a rule, it is returned as it is
+func (cmprule *CMPRule) valueError(token, msg string, err error) error {
+	var re ruleError
+	if errors.As(err, &re) {
+		return cmprule.annotateError(err)
+	}
+	return &ParseError{Rule: cmprule.rawRule, Token: token, Column: cmprule.valueColumn(token), Msg: msg, Err: err}
+}
+
+// opError returns an InvalidOperatorError for the operator of the rule and field type t
+func (cmprule *CMPRule) opError(t string) error {
+	return &InvalidOperatorError{Rule: cmprule.rawRule, Token: cmprule.ruleOp, Column: cmprule.opColumn(), Type: t}
+}
+
+// opColumn returns the byte offset of the operator in the rule, the operator is searched after the field name
+func (cmprule *CMPRule) opColumn() int {
+	start := strings.Index(cmprule.rawRule, cmprule.ruleFieldName)
+	if start < 0 {
+		return strings.Index(cmprule.rawRule, cmprule.ruleOp)
+	}
+	start += len(cmprule.ruleFieldName)
+	if i := strings.Index(cmprule.rawRule[start:], cmprule.ruleOp); i >= 0 {
+		return start + i
+	}
+	return -1
+}
+
+// valueColumn returns the byte offset of token in the rule, token is searched after the operator
+func (cmprule *CMPRule) valueColumn(token string) int {
+	start := 0
+	if col := cmprule.opColumn(); col >= 0 {
+		start = col + len(cmprule.ruleOp)
+	}
+	if i := strings.Index(cmprule.rawRule[start:], token); i >= 0 {
+		return start + i
+	}
+	return -1
+}
+
+// typeError returns a TypeMismatchError for the field of the rule, which has unsupported type t
+func (cmprule *CMPRule) typeError(t reflect.Type) error {
+	return &TypeMismatchError{Rule: cmprule.rawRule, Token: cmprule.ruleFieldName, Column: strings.Index(cmprule.rawRule, cmprule.ruleFieldName),
+		Type: t.String(), Msg: fmt.Sprintf("field %v has unsupported type %v", cmprule.ruleFieldName, t)}
+}
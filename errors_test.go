@@ -0,0 +1,81 @@
+// cmprule_test
+package cmprule
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestErrorTypes(t *testing.T) {
+	input := testStructSlice{Ports: []testPort{{State: "up"}}, Num1: 100}
+	cases := []struct {
+		rule   string
+		target interface{}
+		token  string
+		column int
+	}{
+		//parse errors
+		{"Num1 > 100", new(*ParseError), "Num1 > 100", 0},
+		{"Num1 : in : 100", new(*ParseError), "100", 12},
+		{`Ports[0].State : match : "(a" "b"`, new(*ParseError), "(a", 26},
+		{"Ports[1.Speed : == : 1", new(*ParseError), "Ports[1.Speed", 0},
+		{"Num1 : == : 1a", new(*ParseError), "1a", 12},
+		{"Num1 : is : 1 2a", new(*ParseError), "2a", 14},
+		{"Ports[a].Speed : == : 1", new(*ParseError), "a", 6},
+		//field not found
+		{"Num2 : == : 1", new(*FieldNotFoundError), "Num2", 0},
+		{"Ports[0].Speed2 : == : 1", new(*FieldNotFoundError), "Speed2", 9},
+		{"Ports[3].Speed : == : 1", new(*FieldNotFoundError), "3", 6},
+		//type mismatch
+		{"Num1[0] : == : 1", new(*TypeMismatchError), "Num1[0]", 0},
+		{"Ports : == : 1", new(*TypeMismatchError), "Ports", 0},
+		//invalid operator
+		{"Num1 : ~ : 1", new(*InvalidOperatorError), "~", 7},
+		{"Num1 : same : \"1\"", new(*InvalidOperatorError), "same", 7},
+		{`Ports[0].State : > : 1`, new(*InvalidOperatorError), ">", 17},
+	}
+	for _, c := range cases {
+		cmp := NewDefaultCMPRule()
+		err := cmp.ParseRule(c.rule)
+		if err == nil {
+			_, err = cmp.Compare(input)
+		}
+		t.Logf("rule %v, err: %v", c.rule, err)
+		if !errors.As(err, c.target) {
+			t.Fatalf("rule %v expect %T, got %#v", c.rule, reflect.ValueOf(c.target).Elem().Interface(), err)
+		}
+		re := reflect.ValueOf(c.target).Elem().Interface().(ruleError)
+		rule, token, column := re.location()
+		if rule != c.rule || token != c.token || column != c.column {
+			t.Fatalf("rule %v expect token %q at %d, got rule %v, token %q at %d", c.rule, c.token, c.column, rule, token, column)
+		}
+		if c.column >= 0 && c.rule[column:column+len(token)] != token {
+			t.Fatalf("rule %v: token %q is not at column %d", c.rule, token, column)
+		}
+	}
+}
+
+func TestExprErrorColumn(t *testing.T) {
+	cases := []struct {
+		expr   string
+		token  string
+		column int
+	}{
+		{"Num1:==:1 and (Num2:==:2", "(", 14},
+		{"Num1:==:1 and Num1:in:1", "1", 22},
+		{"Num1:==:1 or Num1:~:1", "~", 18},
+		{"Num1:==:1 and", "", 13},
+	}
+	for _, c := range cases {
+		_, err := ParseExpr(c.expr)
+		t.Logf("expr %v, err: %v", c.expr, err)
+		var pe *ParseError
+		if !errors.As(err, &pe) {
+			t.Fatalf("expr %v expect ParseError, got %v", c.expr, err)
+		}
+		if pe.Rule != c.expr || pe.Token != c.token || pe.Column != c.column {
+			t.Fatalf("expr %v expect token %q at %d, got rule %v, token %q at %d", c.expr, c.token, c.column, pe.Rule, pe.Token, pe.Column)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright 2020 Hu Jun. All rights reserved.
+// This project is licensed under the terms of the MIT license.
+// license that can be found in the LICENSE file.
+
+package cmprule
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldValue is a resolved field and its value
+type FieldValue struct {
+	// Path is the resolved field name, like "Ports[1].Errors" for rule field name "Ports[*].Errors"
+	Path string
+	// Value is the actual value of the field
+	Value interface{}
+	// Result is the compare result of the value
+	Result bool
+}
+
+// CompareResult is the detailed result of a comparison, returned by CompareDetailed
+type CompareResult struct {
+	// Rule is the raw rule or expression text
+	Rule string
+	// Field is the field name in the rule
+	Field string
+	// Op is the compare operator
+	Op string
+	// Expected is the list of parsed expected values, like min and max for a range
+	Expected []string
+	// Values is the list of compared fields, there are multiple fields if the field name contains wildcard;
+	// only the fields compared before the result is known are included
+	Values []FieldValue
+	// Result is the compare result
+	Result bool
+	// Err is non-nil if the comparison could not be done
+	Err error
+	// SubResults is the list of results of rules evaluated in an expression, nil for a single rule
+	SubResults []*CompareResult
+	// Refs is the list of fields referred by the value like "$RxPkts", with their actual values
+	Refs []FieldValue
+}
+
+// String returns a human-readable explanation like `Num1 = -120, expected > 100`;
+// for an expression, explanations of evaluated rules are joined by "; "
+func (r *CompareResult) String() string {
+	if r.SubResults != nil {
+		var parts []string
+		for _, sub := range r.SubResults {
+			parts = append(parts, sub.String())
+		}
+		return strings.Join(parts, "; ")
+	}
+	if r.Err != nil {
+		return fmt.Sprintf("%v: %v", r.Field, r.Err)
+	}
+	var actual []string
+	for _, v := range r.Values {
+		actual = append(actual, fmt.Sprintf("%v = %v", v.Path, formatValue(v.Value)))
+	}
+	s := strings.Join(actual, ", ")
+	switch {
+	case len(r.Values) == 0:
+		s = r.Field + " has no element"
+	case len(r.Values) > 1 || r.Values[0].Path != r.Field:
+		s = r.Field + ": " + s
+	}
+	expected := r.Expected
+	if isStrOp(r.Op) {
+		expected = nil
+		for _, e := range r.Expected {
+			expected = append(expected, strconv.Quote(e))
+		}
+	}
+	s = fmt.Sprintf("%v, expected %v %v", s, r.Op, strings.Join(expected, " "))
+	for _, ref := range r.Refs {
+		s += fmt.Sprintf(", %v = %v", ref.Path, formatValue(ref.Value))
+	}
+	return s
+}
+
+// formatValue returns v as a string in the format used by default rule format;
+// a time.Time in UTC without fractional seconds is formatted as TimeFMTStr, otherwise time.RFC3339Nano
+func formatValue(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return strconv.Quote(val)
+	case time.Time:
+		if val.Nanosecond() != 0 || val.Location() != time.UTC {
+			return val.Format(time.RFC3339Nano)
+		}
+		return val.Format(TimeFMTStr)
+	case net.IP:
+		return val.String()
+	case net.IPNet:
+		return val.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// isStrOp returns true if op is a compare operator for string
+func isStrOp(op string) bool {
+	switch op {
+	case opStrContain, opStrDiffer, opStrNotContain, opStrSame, opStrPrefix, opStrSuffix,
+		opStrContainI, opStrDifferI, opStrNotContainI, opStrSameI, opStrPrefixI, opStrSuffixI,
+		opStrMatch, opStrNotMatch, opStrMatchI, opStrNotMatchI, opStrLike, opStrNotLike:
+		return true
+	}
+	return false
+}
+
+// expectedValues returns the parsed values of the rule
+func (cmprule *CMPRule) expectedValues() []string {
+	switch cmprule.ruleOp {
+	case opNumIN, opNumNotIN:
+		return []string{cmprule.numMinStr, cmprule.numMaxStr}
+	case opNumIs, opNumNot:
+		return cmprule.numListStr
+	case opNumApprox, opNumNotApprox, opNumApproxWord, opNumNotApproxWord:
+		return []string{cmprule.approxValStr, cmprule.approxTolStr}
+	case opIPWithin, opIPNotWithin:
+		var r []string
+		for _, prefix := range cmprule.ipNetList {
+			r = append(r, prefix.String())
+		}
+		return r
+	}
+	if isStrOp(cmprule.ruleOp) {
+		return cmprule.strList
+	}
+	return []string{cmprule.ruleVal}
+}
+
+// CompareDetailed is same as Compare, except it returns a CompareResult,
+// which includes the resolved fields, actual values and expected values
+func (cmprule *CMPRule) CompareDetailed(input interface{}) *CompareResult {
+	r := &CompareResult{
+		Rule:     cmprule.rawRule,
+		Field:    cmprule.ruleFieldName,
+		Op:       cmprule.ruleOp,
+		Expected: cmprule.expectedValues(),
+	}
+	compare, refs, err := cmprule.elementComparer(input)
+	if err != nil {
+		r.Err = cmprule.annotateError(err)
+		return r
+	}
+	r.Result, r.Err = cmprule.walkRuleField(input, func(name string, v interface{}) (bool, error) {
+		result, err := compare(v)
+		r.Values = append(r.Values, FieldValue{Path: name, Value: v, Result: result})
+		return result, err
+	}, func(v FieldValue) {
+		r.Refs = append(r.Refs, v)
+	})
+	r.Refs = append(r.Refs, refs...)
+	r.Err = cmprule.annotateError(r.Err)
+	return r
+}
+
+// Explain compares input like Compare, and returns a human-readable explanation of the result,
+// like `Num1 = -120, expected > 100`
+func (cmprule *CMPRule) Explain(input interface{}) string {
+	return cmprule.CompareDetailed(input).String()
+}
+
+// CompareDetailed is same as Compare, except it returns a CompareResult;
+// if the expression is a single rule, the result is same as the one of CMPRule.CompareDetailed,
+// otherwise SubResults contains results of evaluated rules
+func (e *Expr) CompareDetailed(input interface{}) *CompareResult {
+	if n, ok := e.root.(*exprRuleNode); ok {
+		return n.rule.CompareDetailed(input)
+	}
+	r := &CompareResult{Rule: e.rawExpr, SubResults: []*CompareResult{}}
+	r.Result, r.Err = e.root.compare(input, &r.SubResults)
+	return r
+}
+
+// Explain compares input like Compare, and returns a human-readable explanation of the result
+func (e *Expr) Explain(input interface{}) string {
+	return e.CompareDetailed(input).String()
+}
@@ -0,0 +1,125 @@
+// cmprule_test
+package cmprule
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExplain(t *testing.T) {
+	stamp, _ := time.Parse(TimeFMTStr, "2020/03/31T15:00:00")
+	input := struct {
+		Num1     int
+		Str1     string
+		Stamp1   time.Time
+		IP1      net.IP
+		Ports    []testPort
+		Empty    []testPort
+		Sessions map[string]testPort
+	}{
+		Num1:   -120,
+		Str1:   "test1",
+		Stamp1: stamp,
+		IP1:    net.ParseIP("1.1.1.1"),
+		Ports: []testPort{
+			{Errors: 0}, {Errors: 3}, {Errors: 5},
+		},
+		Sessions: map[string]testPort{
+			"peer2": {State: "down"},
+			"peer1": {State: "up"},
+		},
+	}
+	cases := []struct {
+		rule    string
+		result  bool
+		explain string
+	}{
+		{"Num1:>:100", false, "Num1 = -120, expected > 100"},
+		{"Num1:in:-200 0", true, "Num1 = -120, expected in -200 0"},
+		{"Num1:is:1 2 3", false, "Num1 = -120, expected is 1 2 3"},
+		{`Str1:same:"a" "b"`, false, `Str1 = "test1", expected same "a" "b"`},
+		{"Stamp1:<:2010/01/01T00:00:00", false, "Stamp1 = 2020/03/31T15:00:00, expected < 2010/01/01T00:00:00"},
+		{"IP1:within:2.2.2.0/24", false, "IP1 = 1.1.1.1, expected within 2.2.2.0/24"},
+		{"Ports[*].Errors:==:0", false, "Ports[*].Errors: Ports[0].Errors = 0, Ports[1].Errors = 3, expected == 0"},
+		{"any(Ports).Errors:>:4", true, "any(Ports).Errors: Ports[0].Errors = 0, Ports[1].Errors = 3, Ports[2].Errors = 5, expected > 4"},
+		{"Ports[2].Errors:>:4", true, "Ports[2].Errors = 5, expected > 4"},
+		{"Empty[*].Errors:>:4", true, "Empty[*].Errors has no element, expected > 4"},
+		{`Sessions[*].State:same:"up"`, false, `Sessions[*].State: Sessions["peer1"].State = "up", Sessions["peer2"].State = "down", expected same "up"`},
+		{`Sessions.peer1.State:same:"up"`, true, `Sessions.peer1.State = "up", expected same "up"`},
+		{"Num2:>:100", false, "Num2: field Num2 doesn't exist in struct { Num1 int; Str1 string; Stamp1 time.Time; IP1 net.IP; Ports []cmprule.testPort; Empty []cmprule.testPort; Sessions map[string]cmprule.testPort }"},
+	}
+	cmp := NewDefaultCMPRule()
+	for _, c := range cases {
+		if err := cmp.ParseRule(c.rule); err != nil {
+			t.Fatal(err)
+		}
+		r := cmp.CompareDetailed(input)
+		t.Logf("rule %v: %v", c.rule, r)
+		if r.Result != c.result {
+			t.Fatalf("rule %v expect %v, got %v", c.rule, c.result, r.Result)
+		}
+		if r.Rule != c.rule {
+			t.Fatalf("expect rule %v, got %v", c.rule, r.Rule)
+		}
+		if cmp.Explain(input) != c.explain {
+			t.Fatalf("rule %v expect explanation\n%v\ngot\n%v", c.rule, c.explain, cmp.Explain(input))
+		}
+	}
+	if err := cmp.ParseRule("Ports[*].Errors:in:0 4"); err != nil {
+		t.Fatal(err)
+	}
+	r := cmp.CompareDetailed(input)
+	expected := []FieldValue{
+		{"Ports[0].Errors", 0, true},
+		{"Ports[1].Errors", 3, true},
+		{"Ports[2].Errors", 5, false},
+	}
+	if !reflect.DeepEqual(r.Values, expected) {
+		t.Fatalf("expect %+v, got %+v", expected, r.Values)
+	}
+	if !reflect.DeepEqual(r.Expected, []string{"0", "4"}) {
+		t.Fatalf("unexpected expected values %v", r.Expected)
+	}
+}
+
+func TestExplainExpr(t *testing.T) {
+	input := testStruct{Num1: -120, Str1: "test1"}
+	e, err := ParseExpr(`Num1:>:0 or not Str1:same:"test1" or Num1:==:1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := e.CompareDetailed(input)
+	explain := `Num1 = -120, expected > 0; Str1 = "test1", expected same "test1"; Num1 = -120, expected == 1`
+	if r.Result || r.Err != nil || e.Explain(input) != explain || len(r.SubResults) != 3 {
+		t.Fatalf("unexpected result %v, %v, %v", r.Result, r.Err, e.Explain(input))
+	}
+	//short-circuit, only evaluated rules are included
+	e, err = ParseExpr(`Num1:<:0 or Num1:==:1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r = e.CompareDetailed(input)
+	if !r.Result || len(r.SubResults) != 1 || r.Rule != `Num1:<:0 or Num1:==:1` {
+		t.Fatalf("unexpected result %+v", r)
+	}
+	//single rule
+	e, err = ParseExpr(`Num1:<:0`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r = e.CompareDetailed(input)
+	if !r.Result || r.SubResults != nil || r.Field != "Num1" {
+		t.Fatalf("unexpected result %+v", r)
+	}
+	//RuleSet
+	rs := NewRuleSet()
+	if err := rs.ParseRules("Num1:>:0"); err != nil {
+		t.Fatal(err)
+	}
+	results := rs.Evaluate(input)
+	if results[0].Detail.String() != "Num1 = -120, expected > 0" {
+		t.Fatalf("unexpected detail %v", results[0].Detail)
+	}
+}
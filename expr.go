@@ -0,0 +1,318 @@
+// Copyright 2020 Hu Jun. All rights reserved.
+// This project is licensed under the terms of the MIT license.
+// license that can be found in the LICENSE file.
+
+package cmprule
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// boolean operators of an expression
+const (
+	exprAnd = "and"
+	exprOr  = "or"
+	exprNot = "not"
+)
+
+// exprNode compares input, if details is not nil, results of evaluated rules are appended to it;
+// validate checks every rule in the node against type t, see CMPRule.Validate
+type exprNode interface {
+	compare(input interface{}, details *[]*CompareResult) (bool, error)
+	validate(t reflect.Type) error
+}
+
+type exprAndNode struct {
+	left, right exprNode
+}
+
+// short-circuit: right is not evaluated if left is false
+func (n *exprAndNode) compare(input interface{}, details *[]*CompareResult) (bool, error) {
+	r, err := n.left.compare(input, details)
+	if err != nil || !r {
+		return false, err
+	}
+	return n.right.compare(input, details)
+}
+
+type exprOrNode struct {
+	left, right exprNode
+}
+
+// short-circuit: right is not evaluated if left is true
+func (n *exprOrNode) compare(input interface{}, details *[]*CompareResult) (bool, error) {
+	r, err := n.left.compare(input, details)
+	if err != nil || r {
+		return r, err
+	}
+	return n.right.compare(input, details)
+}
+
+type exprNotNode struct {
+	node exprNode
+}
+
+func (n *exprNotNode) compare(input interface{}, details *[]*CompareResult) (bool, error) {
+	r, err := n.node.compare(input, details)
+	if err != nil {
+		return false, err
+	}
+	return !r, nil
+}
+
+type exprRuleNode struct {
+	rule *CMPRule
+}
+
+func (n *exprRuleNode) compare(input interface{}, details *[]*CompareResult) (bool, error) {
+	if details == nil {
+		return n.rule.Compare(input)
+	}
+	r := n.rule.CompareDetailed(input)
+	*details = append(*details, r)
+	return r.Result, r.Err
+}
+
+// Expr represents a boolean expression of rules, see package doc for the format
+type Expr struct {
+	rawExpr string
+	root    exprNode
+}
+
+// ParseExpr parses a boolean expression of rules,
+// each rule in the expression is parsed by a CMPRule instance created by NewDefaultCMPRule
+func ParseExpr(rawexpr string) (*Expr, error) {
+	return ParseExprWithFunc(rawexpr, NewDefaultCMPRule)
+}
+
+// ParseExprWithFunc is same as ParseExpr,
+// except each rule in the expression is parsed by a CMPRule instance created by newRule
+func ParseExprWithFunc(rawexpr string, newRule func() *CMPRule) (*Expr, error) {
+	p := &exprParser{input: rawexpr, newRule: newRule}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.input) {
+		return nil, p.parseError(p.input[p.pos:], p.pos, fmt.Sprintf("unexpected %q at column %d in expression %v", p.input[p.pos:], p.pos, rawexpr), nil)
+	}
+	return &Expr{rawExpr: rawexpr, root: root}, nil
+}
+
+// Compare input, which must be a struct, or a map/slice like a decoded JSON document, against the expression,
+// rules are evaluated from left to right, and evaluation stops as soon as the result is known;
+// return a non-nil error if any evaluated rule fails to do the comparison
+func (e *Expr) Compare(input interface{}) (bool, error) {
+	return e.root.compare(input, nil)
+}
+
+// String returns the raw expression text
+func (e *Expr) String() string {
+	return e.rawExpr
+}
+
+// exprParser is a recursive descent parser, precedence from low to high: or, and, not
+type exprParser struct {
+	input   string
+	pos     int
+	newRule func() *CMPRule
+}
+
+// parseError returns a ParseError for token at column of the expression
+func (p *exprParser) parseError(token string, column int, msg string, err error) error {
+	return &ParseError{Rule: p.input, Token: token, Column: column, Msg: msg, Err: err}
+}
+
+func (p *exprParser) skipSpace() {
+	for p.pos < len(p.input) && isExprSpace(p.input[p.pos]) {
+		p.pos++
+	}
+}
+
+func isExprSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// keywordAt returns true if the keyword kw starts at position i as a whole word, keywords are case-sensitive;
+// a word followed by ':', '.' or '[' is a field name, like "or : == : 1" or "not.x : == : 1", rather than a keyword
+func (p *exprParser) keywordAt(i int, kw string) bool {
+	end := i + len(kw)
+	if end > len(p.input) || p.input[i:end] != kw {
+		return false
+	}
+	if i > 0 && !isExprSpace(p.input[i-1]) && p.input[i-1] != ')' {
+		return false
+	}
+	if end < len(p.input) && !isExprSpace(p.input[end]) && p.input[end] != '(' {
+		return false
+	}
+	next := end
+	for next < len(p.input) && isExprSpace(p.input[next]) {
+		next++
+	}
+	return next == len(p.input) || strings.IndexByte(":.[", p.input[next]) < 0
+}
+
+func (p *exprParser) acceptKeyword(kw string) bool {
+	p.skipSpace()
+	if p.keywordAt(p.pos, kw) {
+		p.pos += len(kw)
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword(exprOr) {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &exprOrNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword(exprAnd) {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &exprAndNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseNot() (exprNode, error) {
+	if p.acceptKeyword(exprNot) {
+		node, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &exprNotNode{node: node}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	p.skipSpace()
+	if p.pos >= len(p.input) {
+		return nil, p.parseError("", p.pos, fmt.Sprintf("unexpected end of expression %v", p.input), nil)
+	}
+	if p.input[p.pos] == '(' && !p.arithGroupAt(p.pos) {
+		start := p.pos
+		p.pos++
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if p.pos >= len(p.input) || p.input[p.pos] != ')' {
+			return nil, p.parseError("(", start, fmt.Sprintf("unclosed parenthesis at column %d in expression %v", start, p.input), nil)
+		}
+		p.pos++
+		return node, nil
+	}
+	return p.parseRule()
+}
+
+// arithGroupAt returns true if the parentheses start at position i is a part of an arithmetic expression in a rule,
+// like "(RxPkts - TxPkts) / RxPkts : < : 0.1", rather than a group of rules; such parentheses contain no ':'
+func (p *exprParser) arithGroupAt(i int) bool {
+	depth := 0
+	inQuote := false
+	for ; i < len(p.input); i++ {
+		c := p.input[i]
+		switch {
+		case inQuote:
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				inQuote = false
+			}
+		case c == '"':
+			inQuote = true
+		case c == ':':
+			return false
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseRule reads a single rule, which ends at a keyword "and"/"or", an unmatched ')' or end of input;
+// double-quoted strings and brackets within the rule are skipped
+func (p *exprParser) parseRule() (exprNode, error) {
+	start := p.pos
+	depth := 0
+	inQuote := false
+loop:
+	for ; p.pos < len(p.input); p.pos++ {
+		c := p.input[p.pos]
+		switch {
+		case inQuote:
+			if c == '\\' {
+				p.pos++
+			} else if c == '"' {
+				inQuote = false
+			}
+		case c == '"':
+			inQuote = true
+		case c == '(' || c == '[':
+			depth++
+		case c == ')' || c == ']':
+			if depth == 0 {
+				break loop
+			}
+			depth--
+		case depth == 0 && (p.keywordAt(p.pos, exprAnd) || p.keywordAt(p.pos, exprOr)):
+			break loop
+		}
+	}
+	if p.pos > len(p.input) {
+		p.pos = len(p.input)
+	}
+	rawrule := strings.TrimSpace(p.input[start:p.pos])
+	if rawrule == "" {
+		return nil, p.parseError("", start, fmt.Sprintf("missing rule at column %d in expression %v", start, p.input), nil)
+	}
+	rule := p.newRule()
+	if err := rule.ParseRule(rawrule); err != nil {
+		//locate the offending token of the rule in the expression
+		token, column := rawrule, start
+		var re ruleError
+		if errors.As(err, &re) {
+			var col int
+			_, token, col = re.location()
+			column = -1
+			if col >= 0 {
+				column = start + col
+			}
+		}
+		msg := fmt.Sprintf("failed to parse rule %v at column %d", rawrule, start)
+		if rawrule == strings.TrimSpace(p.input) {
+			msg = fmt.Sprintf("failed to parse rule %v", rawrule)
+		}
+		return nil, p.parseError(token, column, msg, err)
+	}
+	return &exprRuleNode{rule: rule}, nil
+}
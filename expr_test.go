@@ -0,0 +1,106 @@
+// cmprule_test
+package cmprule
+
+import (
+	"testing"
+)
+
+var test_list_expr = []testResult{
+	{"Num1:==:-120", true, false},
+	{"(Num1:==:-120)", true, false},
+	{"Num1:==:-120 and Num_uint1:>:100", true, false},
+	{"Num1:==:-120 and Num_uint1:<:100", false, false},
+	//keywords are case-sensitive, "AND" is a part of the value
+	{"Num1:==:-120 AND Num_uint1:<:100", false, true},
+	{"Num1:==:1 or Num_uint1:>:100", true, false},
+	{"not Num1:==:1", true, false},
+	{"not not Num1:==:1", false, false},
+	{"Num1:==:1 or Num1:==:2 and Num1:==:-120", false, false},
+	{"(Num1:==:1 or Num1:==:-120) and Num1:==:-120", true, false},
+	{"Num1:not:1 2", true, false},
+	{"Num1:notin:1 2 and not (Num_uint1:in:1 100 or Float1:>:100)", true, false},
+	{`Str1:same:"a and b" "test1" and Str1:contain:"(" ")" or Str1:contain:"test"`, true, false},
+	//short-circuit, the right side is not evaluated
+	{"Num1:==:1 and Num_notexist:==:1", false, false},
+	{"Num1:==:-120 or Num_notexist:==:1", true, false},
+	{"Num1:==:-120 and Num_notexist:==:1", false, true},
+	//invalid expressions
+	{"", false, true},
+	{"Num1:==:1 and", false, true},
+	{"(Num1:==:1", false, true},
+	{"Num1:==:1)", false, true},
+	{"Num1:==:1 and ()", false, true},
+	{"Num1:in:1 and Num1:==:1", false, true},
+}
+
+func TestExpr(t *testing.T) {
+	input := testStruct{Num1: -120, Num_uint1: 120, Float1: 12.5, Str1: "test1"}
+	for _, tt := range test_list_expr {
+		e, err := ParseExpr(tt.in)
+		if err != nil {
+			if !tt.expect_err {
+				t.Fatal(err)
+			}
+			t.Logf("input: %v, expected err: %v", tt.in, err)
+			continue
+		}
+		if e.String() != tt.in {
+			t.Fatalf("expect %v, got %v", tt.in, e.String())
+		}
+		result, err := e.Compare(input)
+		t.Logf("input: %v; result: %v, err: %v", tt.in, result, err)
+		if err != nil {
+			if !tt.expect_err {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if tt.expect_err {
+			t.Fatalf("input: %v, expect error", tt.in)
+		}
+		if tt.out_bool != result {
+			t.Fatalf("input: %v, expect %v, got %v", tt.in, tt.out_bool, result)
+		}
+	}
+}
+
+type testStructKeyword struct {
+	Or  int
+	And int
+	Not struct {
+		X int
+	}
+	Tags []string       `cmprule:"not"`
+	Map  map[string]int `cmprule:"or"`
+}
+
+func TestExprKeywordField(t *testing.T) {
+	input := testStructKeyword{Or: 1, And: 2, Tags: []string{"a"}, Map: map[string]int{"x": 3}}
+	input.Not.X = 4
+	for _, tt := range []testResult{
+		{"Or : == : 1", true, false},
+		{"And : == : 2 and Or : == : 1", true, false},
+		{"not Not.X : == : 4", false, false},
+		{"not : same : \"a\"", false, true},
+		{"not[0] : same : \"a\" or Or : == : 0", true, false},
+		{"or.x : == : 3 and not And : == : 1", true, false},
+		{"or . x : == : 3", true, false},
+	} {
+		e, err := ParseExpr(tt.in)
+		if err != nil {
+			t.Fatalf("input: %v, %v", tt.in, err)
+		}
+		result, err := e.Compare(input)
+		t.Logf("input: %v; result: %v, err: %v", tt.in, result, err)
+		if (err != nil) != tt.expect_err {
+			t.Fatalf("input: %v, unexpected err: %v", tt.in, err)
+		}
+		if result != tt.out_bool {
+			t.Fatalf("input: %v, expect %v, got %v", tt.in, tt.out_bool, result)
+		}
+	}
+	rs := NewRuleSet()
+	if err := rs.ParseRules("Or : == : 1", "And : > : 1"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,288 @@
+// Copyright 2020 Hu Jun. All rights reserved.
+// This project is licensed under the terms of the MIT license.
+// license that can be found in the LICENSE file.
+
+package cmprule
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/netip"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// fieldRefPrefix is the prefix of a value refers to another field of the input, like "$RxPkts"
+const fieldRefPrefix = "$"
+
+// fieldRef is a value of a rule refers to fields of the input,
+// like "$RxPkts" or an arithmetic expression like "2 * Latency.Avg"
+type fieldRef struct {
+	raw  string
+	expr arithNode
+}
+
+// value classes of field reference comparison, values of the same class are comparable
+const (
+	classInvalid = iota
+	classNumber
+	classDuration
+	classTime
+	classString
+	classBool
+	classIP
+)
+
+// result of orderValues for values can't be ordered, like NaN
+const orderUnordered = 2
+
+// valueClass returns the class of type t for field reference comparison
+func valueClass(t reflect.Type) int {
+	switch t.String() {
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64",
+		"float32", "float64", "json.Number":
+		return classNumber
+	case "time.Duration":
+		return classDuration
+	case "time.Time":
+		return classTime
+	case "string":
+		return classString
+	case "bool":
+		return classBool
+	case "net.IP", "netip.Addr":
+		return classIP
+	default:
+		return classInvalid
+	}
+}
+
+// parseFieldRef parses the value of a single value rule as a field reference, if it starts with "$" or "-$",
+// or it is an arithmetic expression;
+// the referred fields must be single fields, so wildcard is not allowed
+func (cmprule *CMPRule) parseFieldRef() error {
+	cmprule.ref = nil
+	if detectType(cmprule.ruleOp) != valueSingle ||
+		!(strings.HasPrefix(cmprule.ruleVal, fieldRefPrefix) || strings.HasPrefix(cmprule.ruleVal, arithSub+fieldRefPrefix) ||
+			isArithExpr(cmprule.ruleVal)) {
+		return nil
+	}
+	expr, err := parseArith(cmprule.ruleVal, cmprule.parseFieldNamFunc)
+	if err != nil {
+		return cmprule.valueError(cmprule.ruleVal, "invalid field reference", err)
+	}
+	cmprule.ref = &fieldRef{raw: cmprule.ruleVal, expr: expr}
+	return nil
+}
+
+// checkFieldRefOp returns an error if any of values is a field reference, which is only supported by
+// operators take a single value, like "==" and ">"
+func (cmprule *CMPRule) checkFieldRefOp(values []string) error {
+	if detectType(cmprule.ruleOp) == valueSingle {
+		return nil
+	}
+	for _, v := range values {
+		if strings.HasPrefix(v, fieldRefPrefix) || strings.HasPrefix(v, arithSub+fieldRefPrefix) {
+			return cmprule.valueError(v, fmt.Sprintf("field reference %v is not supported by operator %v", v, cmprule.ruleOp), nil)
+		}
+	}
+	return nil
+}
+
+// elementComparer returns the function compares an element of the field against the rule values;
+// if the value refers to fields, it is evaluated in input, and the referred fields are returned
+func (cmprule *CMPRule) elementComparer(input interface{}) (func(interface{}) (bool, error), []FieldValue, error) {
+	if cmprule.ref == nil {
+		if input != nil {
+			if err := cmprule.checkUnit(reflect.TypeOf(input)); err != nil {
+				return nil, nil, err
+			}
+		}
+		return cmprule.compareElement, nil, nil
+	}
+	var refs []FieldValue
+	refVal, err := cmprule.ref.expr.eval(cmprule.resolver, input, func(v FieldValue) {
+		refs = append(refs, v)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return func(element interface{}) (bool, error) {
+		return cmprule.compareRef(element, refVal)
+	}, refs, nil
+}
+
+// compareRef compares element against value of the referred field, both must be of the same value class
+func (cmprule *CMPRule) compareRef(element, refVal interface{}) (bool, error) {
+	etype, rtype := reflect.TypeOf(element), reflect.TypeOf(refVal)
+	if err := cmprule.checkRefClass(etype, rtype); err != nil {
+		return false, err
+	}
+	for _, v := range []FieldValue{{Path: cmprule.ruleFieldName, Value: element}, {Path: cmprule.ref.raw, Value: refVal}} {
+		if n, ok := v.Value.(json.Number); ok {
+			if _, err := n.Float64(); err != nil {
+				return false, fmt.Errorf("field %v has invalid json number %v", v.Path, n)
+			}
+		}
+	}
+	order := orderValues(element, refVal)
+	switch cmprule.ruleOp {
+	case opNumEq:
+		return order == 0, nil
+	case opNumNotEq:
+		return order != 0, nil
+	case opNumL:
+		return order == 1, nil
+	case opNumLE:
+		return order == 0 || order == 1, nil
+	case opNumS:
+		return order == -1, nil
+	case opNumSE:
+		return order == 0 || order == -1, nil
+	}
+	return false, cmprule.opError(etype.String())
+}
+
+// checkRefClass returns an error if field type t can't be compared with referred field type reft by the operator
+func (cmprule *CMPRule) checkRefClass(t, reft reflect.Type) error {
+	class := valueClass(t)
+	if class == classInvalid {
+		return cmprule.typeError(t)
+	}
+	if valueClass(reft) != class {
+		return &TypeMismatchError{Rule: cmprule.rawRule, Token: cmprule.ref.raw, Column: cmprule.valueColumn(cmprule.ref.raw), Type: reft.String(),
+			Msg: fmt.Sprintf("can't compare %v of type %v with %v of type %v", cmprule.ruleFieldName, t, cmprule.ref.raw, reft)}
+	}
+	if (class == classBool || class == classIP) && cmprule.ruleOp != opNumEq && cmprule.ruleOp != opNumNotEq {
+		return cmprule.opError(t.String())
+	}
+	return nil
+}
+
+// orderValues returns -1, 0 or 1 if a is less than, equal to or greater than b, a and b must be of same value class;
+// it returns orderUnordered if either is NaN, or a and b are not equal and they could only be compared for equality;
+// like == for float values in a rule, NaN equals NaN.
+// integers are compared exactly, a float or json.Number is compared as float64
+func orderValues(a, b interface{}) int {
+	switch av := a.(type) {
+	case time.Time:
+		bv := b.(time.Time)
+		switch {
+		case av.Before(bv):
+			return -1
+		case av.After(bv):
+			return 1
+		}
+		return 0
+	case time.Duration:
+		return orderInt64(int64(av), int64(b.(time.Duration)))
+	case string:
+		return strings.Compare(av, b.(string))
+	case bool:
+		if av == b.(bool) {
+			return 0
+		}
+		return orderUnordered
+	case net.IP, netip.Addr:
+		if ipEqual(ipField(av), ipField(b)) {
+			return 0
+		}
+		return orderUnordered
+	}
+	aval, bval := reflect.ValueOf(a), reflect.ValueOf(b)
+	_, aNum := a.(json.Number)
+	_, bNum := b.(json.Number)
+	if !aNum && !bNum && isIntKind(aval.Kind()) && isIntKind(bval.Kind()) {
+		aSigned, bSigned := isSignedKind(aval.Kind()), isSignedKind(bval.Kind())
+		switch {
+		case aSigned && bSigned:
+			return orderInt64(aval.Int(), bval.Int())
+		case !aSigned && !bSigned:
+			return orderUint64(aval.Uint(), bval.Uint())
+		case aSigned:
+			if aval.Int() < 0 {
+				return -1
+			}
+			return orderUint64(uint64(aval.Int()), bval.Uint())
+		default:
+			if bval.Int() < 0 {
+				return 1
+			}
+			return orderUint64(aval.Uint(), uint64(bval.Int()))
+		}
+	}
+	af, bf := toFloat64(a), toFloat64(b)
+	switch {
+	case floatEqual(af, bf):
+		return 0
+	case math.IsNaN(af) || math.IsNaN(bf):
+		return orderUnordered
+	case af < bf:
+		return -1
+	}
+	return 1
+}
+
+func orderInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func orderUint64(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func isIntKind(k reflect.Kind) bool {
+	return isSignedKind(k) || (k >= reflect.Uint && k <= reflect.Uint64)
+}
+
+func isSignedKind(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Int64
+}
+
+// toFloat64 returns numberic value v as float64, an invalid json.Number is NaN
+func toFloat64(v interface{}) float64 {
+	if n, ok := v.(json.Number); ok {
+		f, err := n.Float64()
+		if err != nil {
+			return math.NaN()
+		}
+		return f
+	}
+	val := reflect.ValueOf(v)
+	switch {
+	case isSignedKind(val.Kind()):
+		return float64(val.Int())
+	case isIntKind(val.Kind()):
+		return float64(val.Uint())
+	default:
+		return val.Float()
+	}
+}
+
+// checkRefType returns an error if the field of type t can't be compared with the referred field in root type,
+// nothing is checked if the type of either field could only be known at compare time
+func (cmprule *CMPRule) checkRefType(t, root reflect.Type) error {
+	reft, err := cmprule.ref.expr.typeOf(cmprule.resolver, root)
+	if err != nil {
+		return cmprule.annotateError(err)
+	}
+	if t.Kind() == reflect.Interface || reft.Kind() == reflect.Interface {
+		return nil
+	}
+	return cmprule.checkRefClass(t, reft)
+}
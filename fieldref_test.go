@@ -0,0 +1,152 @@
+// cmprule_test
+package cmprule
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testStructRef struct {
+	RxPkts  uint64
+	TxPkts  uint64
+	Drops   int
+	Rate    float64
+	NaN     float64
+	Start   time.Time
+	End     time.Time
+	Timeout time.Duration
+	Elapsed time.Duration
+	Name    string
+	Alias   string
+	Up      bool
+	Enabled bool
+	Addr    net.IP
+	Peer    net.IP
+	Limit   *int
+	Nil     *int
+	Ports   []testPort
+	Doc     map[string]interface{}
+}
+
+var test_list_fieldref = []testResult{
+	{"RxPkts : >= : $TxPkts", true, false},
+	{"TxPkts : >= : $RxPkts", false, false},
+	{"TxPkts : < : $RxPkts", true, false},
+	{"TxPkts : != : $RxPkts", true, false},
+	{"RxPkts : == : $RxPkts", true, false},
+	{"Drops : < : $TxPkts", true, false},
+	{"RxPkts : > : $Drops", true, false},
+	{"TxPkts : == : $Rate", true, false},
+	{"Rate : <= : $TxPkts", true, false},
+	{"NaN : == : $NaN", true, false},
+	{"NaN : > : $Rate", false, false},
+	{"NaN : <= : $Rate", false, false},
+	{"End : > : $Start", true, false},
+	{"Start : == : $End", false, false},
+	{"Elapsed : < : $Timeout", true, false},
+	{"Name : == : $Alias", true, false},
+	{"Name : > : $Alias", false, false},
+	{"Up : != : $Enabled", true, false},
+	{"Addr : == : $Peer", true, false},
+	{"Ports[*].Errors : <= : $Limit", false, false},
+	{"any(Ports).Errors : > : $Limit", true, false},
+	{"Ports[1].Errors : == : $Limit", true, false},
+	{"Doc.rx : == : $RxPkts", true, false},
+	{"RxPkts : == : $Doc.rx", true, false},
+	{"Doc.tx : == : $RxPkts", false, true},
+	{"RxPkts : == : $Timeout", false, true},
+	{"Name : == : $RxPkts", false, true},
+	{"Up : > : $Enabled", false, true},
+	{"Addr : < : $Peer", false, true},
+	{"RxPkts : == : $Nil", false, true},
+	{"RxPkts : == : $NotExist", false, true},
+}
+
+func TestFieldRef(t *testing.T) {
+	limit := 3
+	start := time.Date(2020, 3, 31, 15, 0, 0, 0, time.UTC)
+	input := testStructRef{
+		RxPkts: 100, TxPkts: 90, Drops: -1, Rate: 90.0, NaN: math.NaN(),
+		Start: start, End: start.Add(time.Nanosecond), Timeout: time.Second, Elapsed: 500 * time.Millisecond,
+		Name: "eth0", Alias: "eth0", Up: true, Enabled: false,
+		Addr: net.ParseIP("1.1.1.1"), Peer: net.ParseIP("::ffff:1.1.1.1"),
+		Limit: &limit,
+		Ports: []testPort{{Errors: 0}, {Errors: 3}, {Errors: 5}},
+		Doc:   map[string]interface{}{"rx": json.Number("100"), "tx": json.Number("abc")},
+	}
+	tableTest(input, test_list_fieldref, t)
+}
+
+func TestFieldRefParse(t *testing.T) {
+	for _, r := range []string{"RxPkts : == : $", "RxPkts : == : $Ports[*].Errors", "RxPkts : == : $any(Ports).Errors"} {
+		rule := NewDefaultCMPRule()
+		err := rule.ParseRule(r)
+		var pe *ParseError
+		if !errors.As(err, &pe) {
+			t.Fatalf("rule %v returns %v, expect a ParseError", r, err)
+		}
+		t.Logf("rule %v returns expected error %v", r, err)
+	}
+	//field reference is only supported by operators take a single value
+	for _, r := range []string{"RxPkts : in : $TxPkts 200", "RxPkts : notin : 1 -$Drops", "RxPkts : is : 1 $TxPkts",
+		"Rate : ~= : $Rate 1%", "Name : same : $Alias", "Addr : within : $Peer"} {
+		rule := NewDefaultCMPRule()
+		err := rule.ParseRule(r)
+		var pe *ParseError
+		if !errors.As(err, &pe) || pe.Column < 0 || !strings.Contains(pe.Msg, "is not supported by operator") {
+			t.Fatalf("rule %v returns %#v, expect a ParseError of unsupported field reference", r, err)
+		}
+		t.Logf("rule %v returns expected error %v", r, err)
+	}
+	if err := NewDefaultCMPRule().ParseRuleDef(RuleDef{Field: "RxPkts", Op: "is", Value: []interface{}{1, "$TxPkts"}}); err == nil {
+		t.Fatal("expect error for field reference in a list")
+	}
+	if err := NewDefaultCMPRule().ParseRuleDef(RuleDef{Field: "Name", Op: "same", Value: "$Alias"}); err != nil {
+		t.Fatal(err)
+	}
+	rule := NewDefaultCMPRule()
+	if err := rule.ParseRule("TxPkts : >= : $RxPkts"); err != nil {
+		t.Fatal(err)
+	}
+	input := testStructRef{RxPkts: 100, TxPkts: 90}
+	if e := rule.Explain(input); e != "TxPkts = 90, expected >= $RxPkts, RxPkts = 100" {
+		t.Fatalf("unexpected explanation %v", e)
+	}
+	if e := rule.Explain(struct{ TxPkts int }{}); e != "TxPkts: field RxPkts doesn't exist in struct { TxPkts int }" {
+		t.Fatalf("unexpected explanation %v", e)
+	}
+	//a rule parsed again without reference must not keep the previous reference
+	if err := rule.ParseRule("TxPkts : >= : 90"); err != nil {
+		t.Fatal(err)
+	}
+	if r, err := rule.Compare(input); err != nil || !r {
+		t.Fatalf("rule %v returns %v, %v", rule, r, err)
+	}
+	if err := rule.ParseRuleDef(RuleDef{Field: "TxPkts", Op: "<", Value: "$RxPkts"}); err != nil {
+		t.Fatal(err)
+	}
+	if r, err := rule.Compare(input); err != nil || !r {
+		t.Fatalf("rule %v returns %v, %v", rule, r, err)
+	}
+}
+
+func TestFieldRefValidate(t *testing.T) {
+	validateTest(reflect.TypeOf(testStructRef{}), []string{
+		"RxPkts : >= : $TxPkts",
+		"Rate : >= : $Drops",
+		"End : > : $Start",
+		"Ports[*].Errors : <= : $Limit",
+		"Doc.rx : == : $RxPkts",
+		"RxPkts : == : $Doc.rx",
+	}, []string{
+		"RxPkts : == : $Timeout",
+		"RxPkts : == : $NotExist",
+		"Up : >= : $Enabled",
+	}, t)
+}
@@ -0,0 +1,281 @@
+// Copyright 2020 Hu Jun. All rights reserved.
+// This project is licensed under the terms of the MIT license.
+// license that can be found in the LICENSE file.
+
+package cmprule
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// IP address property operators, the value is a bool
+const (
+	opIPIs4       = "is4"
+	opIPIs6       = "is6"
+	opIPPrivate   = "private"
+	opIPLoopback  = "loopback"
+	opIPMulticast = "multicast"
+)
+
+// isIPPropertyOp returns true if op is an IP address property operator
+func isIPPropertyOp(op string) bool {
+	switch op {
+	case opIPIs4, opIPIs6, opIPPrivate, opIPLoopback, opIPMulticast:
+		return true
+	}
+	return false
+}
+
+// isIPType returns true if tname is the name of a supported IP address or prefix type
+func isIPType(tname string) bool {
+	switch tname {
+	case "net.IP", "net.IPNet", "netip.Addr", "netip.Prefix":
+		return true
+	}
+	return false
+}
+
+// ipValue is an IP address, or an IP prefix if isPrefix is true
+type ipValue struct {
+	addr     net.IP
+	prefix   *net.IPNet
+	isPrefix bool
+}
+
+// ipValues is rule values parsed for IP address and prefix types
+type ipValues struct {
+	single ipValue
+	min    net.IP
+	max    net.IP
+	list   []ipValue
+}
+
+func defaultParseIPFunc(ipstr string) (net.IP, error) {
+	ip := net.ParseIP(ipstr)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP address %v", ipstr)
+	}
+	return ip, nil
+}
+
+// ipField returns field value v of type net.IP, net.IPNet, netip.Addr or netip.Prefix as an ipValue,
+// a prefix is masked; an invalid address or prefix is nil
+func ipField(v interface{}) ipValue {
+	switch val := v.(type) {
+	case net.IP:
+		return ipValue{addr: val}
+	case net.IPNet:
+		return ipValue{prefix: &net.IPNet{IP: val.IP.Mask(val.Mask), Mask: val.Mask}, isPrefix: true}
+	case netip.Addr:
+		return ipValue{addr: net.IP(val.AsSlice())}
+	case netip.Prefix:
+		if !val.IsValid() {
+			return ipValue{isPrefix: true}
+		}
+		p := val.Masked()
+		return ipValue{prefix: &net.IPNet{IP: p.Addr().AsSlice(), Mask: net.CIDRMask(p.Bits(), p.Addr().BitLen())}, isPrefix: true}
+	}
+	return ipValue{}
+}
+
+// parseIPValue parses s into an IP prefix if it contains "/", otherwise into an IP address
+func (cmprule *CMPRule) parseIPValue(s string) (ipValue, error) {
+	if strings.Contains(s, "/") {
+		_, prefix, err := net.ParseCIDR(s)
+		return ipValue{prefix: prefix, isPrefix: true}, err
+	}
+	ip, err := cmprule.parseIPFunc(s)
+	return ipValue{addr: ip}, err
+}
+
+// prepareIP returns rule values parsed for IP address and prefix types, the parsed values are cached
+func (cmprule *CMPRule) prepareIP() (*ipValues, error) {
+	cmprule.preparedLock.Lock()
+	defer cmprule.preparedLock.Unlock()
+	if cmprule.preparedIP != nil {
+		return cmprule.preparedIP, nil
+	}
+	values := new(ipValues)
+	var err error
+	switch detectType(cmprule.ruleOp) {
+	case valueSingle:
+		values.single, err = cmprule.parseIPValue(cmprule.ruleVal)
+		if err != nil {
+			return nil, cmprule.valueError(cmprule.ruleVal, fmt.Sprintf("can't parse %v into IP address or prefix", cmprule.ruleVal), err)
+		}
+	case valueRange:
+		values.min, err = cmprule.parseIPFunc(cmprule.numMinStr)
+		if err != nil {
+			return nil, cmprule.valueError(cmprule.numMinStr, "invalid range value", err)
+		}
+		values.max, err = cmprule.parseIPFunc(cmprule.numMaxStr)
+		if err != nil {
+			return nil, cmprule.valueError(cmprule.numMaxStr, "invalid range value", err)
+		}
+		if (values.min.To4() == nil) != (values.max.To4() == nil) {
+			return nil, cmprule.valueError(cmprule.ruleVal, "invalid range value, min and max value are of different address family", nil)
+		}
+		if bytes.Compare(values.max.To16(), values.min.To16()) < 0 {
+			return nil, cmprule.valueError(cmprule.ruleVal, "invalid range value, max value is smaller than min value", nil)
+		}
+	case valueList:
+		values.list = []ipValue{}
+		for _, str := range cmprule.numListStr {
+			v, err := cmprule.parseIPValue(str)
+			if err != nil {
+				return nil, cmprule.valueError(str, "invalid list value", err)
+			}
+			values.list = append(values.list, v)
+		}
+	}
+	cmprule.preparedIP = values
+	return values, nil
+}
+
+// checkIPOp returns an error if the operator or values of the rule are invalid for IP type tname,
+// isPrefix is true for a prefix type; it returns the parsed values for operators have them
+func (cmprule *CMPRule) checkIPOp(tname string, isPrefix bool) (*ipValues, error) {
+	switch {
+	case cmprule.ruleOp == opIPWithin || cmprule.ruleOp == opIPNotWithin:
+		return nil, nil
+	case isIPPropertyOp(cmprule.ruleOp):
+		if isPrefix && cmprule.ruleOp != opIPIs4 && cmprule.ruleOp != opIPIs6 {
+			return nil, cmprule.opError(tname)
+		}
+		if _, err := cmprule.parseBoolFunc(cmprule.ruleVal); err != nil {
+			return nil, cmprule.valueError(cmprule.ruleVal, fmt.Sprintf("can't parse %v into bool", cmprule.ruleVal), err)
+		}
+		return nil, nil
+	case cmprule.ruleOp == opNumEq || cmprule.ruleOp == opNumNotEq || detectType(cmprule.ruleOp) == valueList:
+	case detectType(cmprule.ruleOp) == valueRange && !isPrefix:
+	default:
+		return nil, cmprule.opError(tname)
+	}
+	values, err := cmprule.prepareIP()
+	if err != nil {
+		return nil, err
+	}
+	check := func(token string, v ipValue) error {
+		if v.isPrefix == isPrefix {
+			return nil
+		}
+		kind := "address"
+		if isPrefix {
+			kind = "prefix"
+		}
+		return cmprule.valueError(token, fmt.Sprintf("%v is not an IP %v, which is expected by %v of type %v", token, kind, cmprule.ruleFieldName, tname), nil)
+	}
+	switch detectType(cmprule.ruleOp) {
+	case valueSingle:
+		err = check(cmprule.ruleVal, values.single)
+	case valueList:
+		for i, v := range values.list {
+			if err = check(cmprule.numListStr[i], v); err != nil {
+				break
+			}
+		}
+	}
+	return values, err
+}
+
+// compareIP compares the IP address or prefix field of type tname
+func (cmprule *CMPRule) compareIP(tname string, field ipValue) (bool, error) {
+	values, err := cmprule.checkIPOp(tname, field.isPrefix)
+	if err != nil {
+		return false, err
+	}
+	switch cmprule.ruleOp {
+	case opIPWithin, opIPNotWithin:
+		within := false
+		for _, prefix := range cmprule.ipNetList {
+			if (!field.isPrefix && prefix.Contains(field.addr)) || (field.isPrefix && prefixWithin(field.prefix, prefix)) {
+				within = true
+				break
+			}
+		}
+		return within == (cmprule.ruleOp == opIPWithin), nil
+	case opIPIs4, opIPIs6, opIPPrivate, opIPLoopback, opIPMulticast:
+		expect, _ := cmprule.parseBoolFunc(cmprule.ruleVal)
+		addr := field.addr
+		if field.isPrefix && field.prefix != nil {
+			addr = field.prefix.IP
+		}
+		var r bool
+		switch cmprule.ruleOp {
+		case opIPIs4:
+			r = addr.To4() != nil
+		case opIPIs6:
+			r = addr.To4() == nil && addr.To16() != nil
+		case opIPPrivate:
+			r = addr.IsPrivate()
+		case opIPLoopback:
+			r = addr.IsLoopback()
+		case opIPMulticast:
+			r = addr.IsMulticast()
+		}
+		return r == expect, nil
+	case opNumEq:
+		return ipEqual(field, values.single), nil
+	case opNumNotEq:
+		return !ipEqual(field, values.single), nil
+	case opNumIN, opNumNotIN:
+		in := (field.addr.To4() == nil) == (values.min.To4() == nil) &&
+			bytes.Compare(field.addr.To16(), values.min.To16()) >= 0 && bytes.Compare(field.addr.To16(), values.max.To16()) <= 0
+		return in == (cmprule.ruleOp == opNumIN), nil
+	}
+	//is, not
+	found := false
+	for _, v := range values.list {
+		if ipEqual(field, v) {
+			found = true
+			break
+		}
+	}
+	return found == (cmprule.ruleOp == opNumIs), nil
+}
+
+// prefixBits returns the address of prefix p, in 4 bytes for IPv4, and the length of p
+func prefixBits(p *net.IPNet) (net.IP, int) {
+	ones, bits := p.Mask.Size()
+	if ip4 := p.IP.To4(); ip4 != nil {
+		if bits == 8*net.IPv6len {
+			ones -= 8 * (net.IPv6len - net.IPv4len)
+		}
+		return ip4, ones
+	}
+	return p.IP.To16(), ones
+}
+
+// prefixWithin returns true if prefix inner is a subnet of, or same as prefix outer
+func prefixWithin(inner, outer *net.IPNet) bool {
+	if inner == nil {
+		return false
+	}
+	ip, ones := prefixBits(inner)
+	outerIP, outerOnes := prefixBits(outer)
+	return len(ip) == len(outerIP) && ones >= outerOnes && outer.Contains(ip)
+}
+
+// ipEqual returns true if a and b are the same address or the same prefix, an invalid address or prefix equals nothing
+func ipEqual(a, b ipValue) bool {
+	if a.isPrefix {
+		if a.prefix == nil || b.prefix == nil {
+			return false
+		}
+		aIP, aOnes := prefixBits(a.prefix)
+		bIP, bOnes := prefixBits(b.prefix)
+		return aIP.Equal(bIP) && len(aIP) == len(bIP) && aOnes == bOnes
+	}
+	return len(a.addr) != 0 && a.addr.Equal(b.addr)
+}
+
+// SetParseIPFunc set f as function to parse a string that represents an IP address into net.IP.
+// this is used by IP address types with operator ==, !=, is, not, in and notin.
+// default function uses net.ParseIP
+func (cmprule *CMPRule) SetParseIPFunc(f func(ipstr string) (net.IP, error)) {
+	cmprule.parseIPFunc = f
+}
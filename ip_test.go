@@ -0,0 +1,119 @@
+// cmprule_test
+package cmprule
+
+import (
+	"net"
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+type testStructIP struct {
+	Addr     net.IP
+	Addr6    net.IP
+	Loopback net.IP
+	Mcast    netip.Addr
+	NAddr    netip.Addr
+	Unset    netip.Addr
+	Subnet   *net.IPNet
+	Route    netip.Prefix
+	Hops     []netip.Addr
+	Gateway  net.IP
+}
+
+var test_list_ip = []testResult{
+	{"Addr : within : 10.0.0.0/8", true, false},
+	{"Addr : notwithin : 10.0.0.0/8", false, false},
+	{"Addr : notwithin : 192.168.0.0/16 2001:db8::/32", true, false},
+	{"Addr : == : 10.0.0.20", true, false},
+	{"Addr : == : ::ffff:10.0.0.20", true, false},
+	{"Addr : != : 10.0.0.20", false, false},
+	{"Addr : is : 10.0.0.1 10.0.0.20", true, false},
+	{"Addr : not : 10.0.0.1 10.0.0.20", false, false},
+	{"Addr : in : 10.0.0.1 10.0.0.50", true, false},
+	{"Addr : in : 10.0.0.21 10.0.0.50", false, false},
+	{"Addr : notin : 10.0.0.21 10.0.0.50", true, false},
+	{"Addr6 : in : 2001:db8:: 2001:db8::ffff", true, false},
+	{"Addr6 : in : 10.0.0.1 10.0.0.50", false, false},
+	{"Addr : in : 10.0.0.50 10.0.0.1", false, true},
+	{"Addr : in : 10.0.0.1 2001:db8::1", false, true},
+	{"Addr : == : 10.0.0.0/8", false, true},
+	{"Addr : == : 10.0.0.256", false, true},
+	{"Addr : > : 10.0.0.1", false, true},
+	{"Addr : is4 : true", true, false},
+	{"Addr6 : is4 : true", false, false},
+	{"Addr6 : is6 : true", true, false},
+	{"Addr : private : true", true, false},
+	{"Addr6 : private : false", true, false},
+	{"Loopback : loopback : true", true, false},
+	{"Mcast : multicast : true", true, false},
+	{"Mcast : within : 224.0.0.0/4", true, false},
+	{"NAddr : == : 192.168.1.1", true, false},
+	{"NAddr : == : $Gateway", true, false},
+	{"Unset : == : 0.0.0.0", false, false},
+	{"Unset : is4 : false", true, false},
+	{"Hops[*] : within : 10.0.0.0/30", true, false},
+	{"any(Hops) : == : 10.0.0.2", true, false},
+	{"Subnet : == : 10.1.2.0/24", true, false},
+	{"Subnet : == : 10.1.0.0/16", false, false},
+	{"Subnet : within : 10.0.0.0/8", true, false},
+	{"Subnet : within : 10.1.2.0/25", false, false},
+	{"Subnet : notwithin : 10.1.2.0/24", false, false},
+	{"Subnet : is4 : true", true, false},
+	{"Subnet : private : true", false, true},
+	{"Subnet : in : 10.0.0.0 10.0.0.1", false, true},
+	{"Subnet : == : 10.1.2.0", false, true},
+	{"Route : == : 2001:db8:1::/48", true, false},
+	{"Route : is : 0.0.0.0/0 ::/0", false, false},
+	{"Route : within : 2001:db8::/32", true, false},
+	{"Route : within : 0.0.0.0/0", false, false},
+	{"Route : is6 : true", true, false},
+}
+
+func TestIPCompare(t *testing.T) {
+	_, subnet, _ := net.ParseCIDR("10.1.2.3/24")
+	input := testStructIP{
+		Addr:     net.ParseIP("10.0.0.20"),
+		Addr6:    net.ParseIP("2001:db8::1"),
+		Loopback: net.ParseIP("::1"),
+		Mcast:    netip.MustParseAddr("224.0.0.5"),
+		NAddr:    netip.MustParseAddr("192.168.1.1"),
+		Subnet:   subnet,
+		Route:    netip.MustParsePrefix("2001:db8:1::1/48"),
+		Hops:     []netip.Addr{netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("10.0.0.2")},
+		Gateway:  net.ParseIP("192.168.1.1"),
+	}
+	tableTest(input, test_list_ip, t)
+}
+
+func TestIPRuleDef(t *testing.T) {
+	input := testStructIP{Addr: net.ParseIP("10.0.0.20")}
+	cmp := NewDefaultCMPRule()
+	if err := cmp.ParseRuleDef(RuleDef{Field: "Addr", Op: "private", Value: true}); err != nil {
+		t.Fatal(err)
+	}
+	if r, err := cmp.Compare(input); err != nil || !r {
+		t.Fatalf("expect true, got %v, %v", r, err)
+	}
+	if err := cmp.ParseRuleDef(RuleDef{Field: "Addr", Op: "private", Value: []interface{}{true, false}}); err == nil {
+		t.Fatal("expect error for more than one value")
+	}
+}
+
+func TestIPValidate(t *testing.T) {
+	validateTest(reflect.TypeOf(testStructIP{}), []string{
+		"Addr : == : ::ffff:10.0.0.20",
+		"Addr : in : 10.0.0.1 10.0.0.50",
+		"NAddr : private : true",
+		"Subnet : == : 10.1.2.0/24",
+		"Route : is6 : true",
+		"Hops[*] : within : 10.0.0.0/30",
+	}, []string{
+		"Addr : > : 10.0.0.1",
+		"Addr : == : 10.0.0.0/8",
+		"Addr : in : 10.0.0.50 10.0.0.1",
+		"Subnet : private : true",
+		"Subnet : in : 10.0.0.0 10.0.0.1",
+		"Route : == : 2001:db8:1::1",
+	}, t)
+}
@@ -0,0 +1,57 @@
+// Copyright 2020 Hu Jun. All rights reserved.
+// This project is licensed under the terms of the MIT license.
+// license that can be found in the LICENSE file.
+
+package cmprule
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// decodeJSON decodes data into a tree of map[string]interface{}, []interface{} and scalar values,
+// JSON numbers are decoded as json.Number, so that integers keep their precision
+func decodeJSON(data []byte) (interface{}, error) {
+	var doc interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to decode JSON document, %w", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("failed to decode JSON document, invalid data after top-level value")
+	}
+	return doc, nil
+}
+
+// CompareJSON decodes data as a JSON document and compares it based on parsed rules, see Compare;
+// a JSON object is accessed like a map, and a JSON array is accessed like a slice,
+// e.g. `sessions[*].state` or `[0].name` when the document is an array
+func (cmprule *CMPRule) CompareJSON(data []byte) (bool, error) {
+	doc, err := decodeJSON(data)
+	if err != nil {
+		return false, err
+	}
+	return cmprule.Compare(doc)
+}
+
+// CompareJSON decodes data as a JSON document and compares it against the expression, see CMPRule.CompareJSON
+func (e *Expr) CompareJSON(data []byte) (bool, error) {
+	doc, err := decodeJSON(data)
+	if err != nil {
+		return false, err
+	}
+	return e.Compare(doc)
+}
+
+// EvaluateJSON decodes data as a JSON document and evaluates every rule in the set against it,
+// see CMPRule.CompareJSON; return a non-nil error if data is not a valid JSON document
+func (rs *RuleSet) EvaluateJSON(data []byte) ([]RuleResult, error) {
+	doc, err := decodeJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	return rs.Evaluate(doc), nil
+}
@@ -0,0 +1,151 @@
+// cmprule_test
+package cmprule
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+var test_json_doc = []byte(`{
+	"name": "core-1",
+	"rx_bytes": 1000,
+	"rate": 12.5,
+	"nothing": null,
+	"sessions": {
+		"peer1": {"state": "up", "errors": 0},
+		"peer2": {"state": "down", "errors": 3}
+	},
+	"ports": [
+		{"id": 1, "speed": 1000},
+		{"id": 2, "speed": 100}
+	],
+	"tags": ["a", "b"]
+}`)
+
+var test_list_json = []testResult{
+	{`name:same:"core-1"`, true, false},
+	{`name:contain:"core"`, true, false},
+	{`rx_bytes:==:1000`, true, false},
+	{`rx_bytes:>:999.5`, true, false},
+	{`rx_bytes:in:1 2000`, true, false},
+	{`rx_bytes:is:1 1000`, true, false},
+	{`rx_bytes:not:1 1000`, false, false},
+	{`rate:<:13`, true, false},
+	{`sessions.peer1.state:same:"up"`, true, false},
+	{`sessions["peer2"].errors:>:0`, true, false},
+	{`any(sessions).state:same:"down"`, true, false},
+	{`sessions[*].errors:==:0`, false, false},
+	{`ports[1].speed:<:1000`, true, false},
+	{`ports[*].id:in:1 2`, true, false},
+	{`any(tags):same:"b"`, true, false},
+	{`nothing:==:1`, false, true},
+	{`notexist:==:1`, false, true},
+	{`ports.speed:==:1`, false, true},
+}
+
+var test_list_json_array = []testResult{
+	{`[0].id:==:1`, true, false},
+	{`[*].speed:>=:100`, true, false},
+	{`any().speed:>:100`, true, false},
+	{`none().speed:>:1000`, true, false},
+	{`[2].id:==:1`, false, true},
+}
+
+func TestJSON(t *testing.T) {
+	for _, tt := range test_list_json {
+		cmp := NewDefaultCMPRule()
+		if err := cmp.ParseRule(tt.in); err != nil {
+			t.Fatal(err)
+		}
+		result, err := cmp.CompareJSON(test_json_doc)
+		t.Logf("input: %v; result: %v, err: %v", tt.in, result, err)
+		if (err != nil) != tt.expect_err {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if result != tt.out_bool {
+			t.Fatalf("expect %v, got %v", tt.out_bool, result)
+		}
+	}
+	var doc interface{}
+	if err := json.Unmarshal(test_json_doc, &doc); err != nil {
+		t.Fatal(err)
+	}
+	tableTest(doc, test_list_json, t)
+	//json.Number
+	dec := json.NewDecoder(bytes.NewReader(test_json_doc))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+	tableTest(doc, test_list_json, t)
+	if err := json.Unmarshal([]byte(`[{"id":1,"speed":1000},{"id":2,"speed":100}]`), &doc); err != nil {
+		t.Fatal(err)
+	}
+	tableTest(doc, test_list_json_array, t)
+	//invalid document
+	cmp := NewDefaultCMPRule()
+	if err := cmp.ParseRule("name:==:1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cmp.CompareJSON([]byte(`{"name":`)); err == nil {
+		t.Fatal("expect error for invalid JSON")
+	}
+}
+
+func TestJSONExprRuleSet(t *testing.T) {
+	e, err := ParseExpr(`rx_bytes:>:100 and (any(ports).speed:<:1000 or name:same:"x")`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := e.CompareJSON(test_json_doc)
+	if err != nil || !r {
+		t.Fatalf("expect true, got %v, %v", r, err)
+	}
+	rs := NewRuleSet()
+	if err := rs.ParseRules(`rx_bytes:>:100`, `rate:>:100`); err != nil {
+		t.Fatal(err)
+	}
+	results, err := rs.EvaluateJSON(test_json_doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !results[0].Passed() || results[1].Passed() {
+		t.Fatalf("unexpected results %+v", results)
+	}
+	if _, err := rs.EvaluateJSON([]byte(`[`)); err == nil {
+		t.Fatal("expect error for invalid JSON")
+	}
+}
+
+func TestJSONLargeInteger(t *testing.T) {
+	doc := []byte(`{"big": 9007199254740993, "huge": 18446744073709551615, "neg": -9223372036854775808, "rate": 0.5}`)
+	for _, tt := range []testResult{
+		{"big : == : 9007199254740993", true, false},
+		{"big : == : 9007199254740992", false, false},
+		{"big : > : 9007199254740992", true, false},
+		{"big : is : 1 9007199254740993", true, false},
+		{"big : > : 1.5e15", true, false},
+		{"huge : == : 18446744073709551615", true, false},
+		{"huge : < : 18446744073709551614", false, false},
+		{"neg : == : -9223372036854775808", true, false},
+		{"rate : == : 0.5", true, false},
+		{"big : == : abc", false, true},
+	} {
+		cmp := NewDefaultCMPRule()
+		if err := cmp.ParseRule(tt.in); err != nil {
+			t.Fatal(err)
+		}
+		result, err := cmp.CompareJSON(doc)
+		t.Logf("input: %v; result: %v, err: %v", tt.in, result, err)
+		if (err != nil) != tt.expect_err {
+			t.Fatalf("unexpected err: %v", err)
+		}
+		if result != tt.out_bool {
+			t.Fatalf("expect %v, got %v", tt.out_bool, result)
+		}
+	}
+	if _, err := NewDefaultCMPRule().CompareJSON([]byte(`{"a": 1} {"b": 2}`)); err == nil {
+		t.Fatal("expect error for data after the document")
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2020 Hu Jun. All rights reserved.
+// This project is licensed under the terms of the MIT license.
+// license that can be found in the LICENSE file.
+
+package cmprule
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// keywords of a rule file
+const (
+	ruleFileComment      = "#"
+	ruleFileContinuation = `\`
+	ruleFileInclude      = "include"
+)
+
+// Source is the location of a rule in a rule file
+type Source struct {
+	// File is the name of the rule file
+	File string
+	// Line is the line number of the rule in File, starting from 1;
+	// for a rule continued over multiple lines, it is the first line
+	Line int
+}
+
+// IsZero returns true if s is not a location in a rule file
+func (s Source) IsZero() bool {
+	return s.File == "" && s.Line == 0
+}
+
+// String returns the location like "file:line"
+func (s Source) String() string {
+	return fmt.Sprintf("%v:%d", s.File, s.Line)
+}
+
+// LoadFile reads rules from rule file name, and adds them to the rule set, see package doc for the format of a rule file;
+// each rule is parsed like ParseRules, and its location in the file is kept as RuleResult.Source;
+// it stops and returns an error at first rule fails to parse, rules before it are kept in the set
+func (rs *RuleSet) LoadFile(name string) error {
+	l := &ruleLoader{rs: rs}
+	return l.loadFile(name)
+}
+
+// Load is same as LoadFile, except the rules are read from r;
+// name is used as the file name of Source, and a relative path of include is relative to the directory of name
+func (rs *RuleSet) Load(r io.Reader, name string) error {
+	l := &ruleLoader{rs: rs}
+	return l.load(r, name)
+}
+
+// ruleLoader loads rule files into rs
+type ruleLoader struct {
+	rs *RuleSet
+	// stack is the absolute paths of files being loaded, used to detect recursive include
+	stack []string
+}
+
+func (l *ruleLoader) loadFile(name string) error {
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return err
+	}
+	for _, f := range l.stack {
+		if f == abs {
+			return fmt.Errorf("recursive include of %v", name)
+		}
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return fmt.Errorf("failed to open rule file, %w", err)
+	}
+	defer f.Close()
+	l.stack = append(l.stack, abs)
+	defer func() { l.stack = l.stack[:len(l.stack)-1] }()
+	return l.load(f, name)
+}
+
+// load reads r line by line, a line ends with `\` is joined with the next rule line
+func (l *ruleLoader) load(r io.Reader, name string) error {
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	var parts []string
+	var src Source
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		//comments and blank lines are skipped, including those within a continued rule
+		if line == "" || strings.HasPrefix(line, ruleFileComment) {
+			continue
+		}
+		if parts == nil {
+			src = Source{File: name, Line: lineNum}
+		}
+		if strings.HasSuffix(line, ruleFileContinuation) {
+			parts = append(parts, strings.TrimSpace(strings.TrimSuffix(line, ruleFileContinuation)))
+			continue
+		}
+		parts = append(parts, line)
+		if err := l.loadLine(strings.Join(parts, " "), src); err != nil {
+			return err
+		}
+		parts = nil
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read rule file %v, %w", name, err)
+	}
+	if parts != nil {
+		return l.loadLine(strings.Join(parts, " "), src)
+	}
+	return nil
+}
+
+// loadLine parses line as a rule or an include directive
+func (l *ruleLoader) loadLine(line string, src Source) error {
+	if path, ok := parseInclude(line); ok {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(src.File), path)
+		}
+		if err := l.loadFile(path); err != nil {
+			return fmt.Errorf("%v: %w", src, err)
+		}
+		return nil
+	}
+	rule, err := ParseExprWithFunc(line, l.rs.newRuleFunc)
+	if err != nil {
+		//err already names the rule
+		return fmt.Errorf("%v: %w", src, err)
+	}
+	l.rs.add(rule, src)
+	return nil
+}
+
+// parseInclude returns the path of an include directive like `include other.rules` or `include "my rules.txt"`;
+// a rule with field name "include" like "include : == : 1" is not a directive
+func parseInclude(line string) (string, bool) {
+	if !strings.HasPrefix(line, ruleFileInclude) {
+		return "", false
+	}
+	rest := line[len(ruleFileInclude):]
+	if rest == "" || !isExprSpace(rest[0]) {
+		return "", false
+	}
+	rest = strings.TrimSpace(rest)
+	if rest == "" || strings.HasPrefix(rest, ":") {
+		return "", false
+	}
+	if path, err := strconv.Unquote(rest); err == nil {
+		return path, true
+	}
+	return rest, true
+}
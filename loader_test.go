@@ -0,0 +1,137 @@
+// cmprule_test
+package cmprule
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeRuleFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "cmprule")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadFile(t *testing.T) {
+	dir := writeRuleFiles(t, map[string]string{
+		"main.rules": `# main rules
+
+Num1 : == : -120
+  # indented comment
+Str1 : same : "a" \
+	"test1"
+include sub/str.rules
+include "sub/num rules.txt"
+Num1 : > : 100
+`,
+		"sub/str.rules": `Str1 : contain : "#test"
+`,
+		"sub/num rules.txt": `Num1 : in : -200 0 \
+	or Float1 : < : 0`,
+	})
+	defer os.RemoveAll(dir)
+	rs := NewRuleSet()
+	if err := rs.LoadFile(filepath.Join(dir, "main.rules")); err != nil {
+		t.Fatal(err)
+	}
+	input := testStruct{Num1: -120, Str1: "#test1"}
+	expected := []struct {
+		rule   string
+		file   string
+		line   int
+		result bool
+	}{
+		{"Num1 : == : -120", "main.rules", 3, true},
+		{`Str1 : same : "a" "test1"`, "main.rules", 5, false},
+		{`Str1 : contain : "#test"`, "sub/str.rules", 1, true},
+		{"Num1 : in : -200 0 or Float1 : < : 0", "sub/num rules.txt", 1, true},
+		{"Num1 : > : 100", "main.rules", 9, false},
+	}
+	results := rs.Evaluate(input)
+	if len(results) != len(expected) {
+		t.Fatalf("expect %d results, got %d", len(expected), len(results))
+	}
+	for i, r := range results {
+		t.Logf("%v: rule %v, result %v, err %v", r.Source, r.Rule, r.Result, r.Err)
+		e := expected[i]
+		if r.Rule != e.rule || r.Source.File != filepath.Join(dir, e.file) || r.Source.Line != e.line || r.Result != e.result || r.Err != nil {
+			t.Fatalf("expect %+v, got %+v", e, r)
+		}
+	}
+}
+
+func TestLoadFileError(t *testing.T) {
+	dir := writeRuleFiles(t, map[string]string{
+		"bad.rules":   "Num1 : == : 1\n\nNum1 : in : 1\n",
+		"inc.rules":   "Num1 : == : 1\ninclude bad.rules\n",
+		"loop.rules":  "include loop2.rules\n",
+		"loop2.rules": "include loop.rules\n",
+		"miss.rules":  "include notexist.rules\n",
+	})
+	defer os.RemoveAll(dir)
+	cases := []struct {
+		file   string
+		errStr string
+	}{
+		{"bad.rules", "bad.rules:3: failed to parse rule"},
+		{"inc.rules", "inc.rules:2: " + filepath.Join(dir, "bad.rules") + ":3:"},
+		{"loop.rules", "recursive include"},
+		{"miss.rules", "failed to open rule file"},
+		{"notexist.rules", "failed to open rule file"},
+	}
+	for _, c := range cases {
+		err := NewRuleSet().LoadFile(filepath.Join(dir, c.file))
+		t.Logf("%v: %v", c.file, err)
+		if err == nil || !strings.Contains(err.Error(), c.errStr) {
+			t.Fatalf("%v: expect error contains %q, got %v", c.file, c.errStr, err)
+		}
+		if strings.Count(err.Error(), "failed to parse rule") > 1 {
+			t.Fatalf("%v: rule is named more than once in error %v", c.file, err)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	rs := NewRuleSet()
+	err := rs.Load(strings.NewReader("include : == : 1\n# comment\nNum1 : == : 1 \\\n"), "reader")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rs.Len() != 2 {
+		t.Fatalf("expect 2 rules, got %d", rs.Len())
+	}
+	results := rs.Evaluate(testStruct{Num1: 1})
+	if results[1].Source.String() != "reader:3" || !results[1].Passed() {
+		t.Fatalf("unexpected result %+v", results[1])
+	}
+	//a comment within a continued rule is not part of the rule
+	rs = NewRuleSet()
+	err = rs.Load(strings.NewReader("Num1 : in : 1 \\\n# note\n\n  2\nNum1 : == : 1\n"), "reader")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rs.Len() != 2 {
+		t.Fatalf("expect 2 rules, got %d", rs.Len())
+	}
+	results = rs.Evaluate(testStruct{Num1: 1})
+	if results[0].Rule != "Num1 : in : 1 2" || results[0].Source.String() != "reader:1" || !results[0].Passed() {
+		t.Fatalf("unexpected result %+v", results[0])
+	}
+	if results[1].Source.String() != "reader:5" {
+		t.Fatalf("unexpected result %+v", results[1])
+	}
+}
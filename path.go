@@ -0,0 +1,526 @@
+// Copyright 2020 Hu Jun. All rights reserved.
+// This project is licensed under the terms of the MIT license.
+// license that can be found in the LICENSE file.
+
+package cmprule
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// tagName is the struct tag key for field name used in rules, like `cmprule:"rx_packets"`
+const tagName = "cmprule"
+
+// FieldNaming specifies how a field name in rule is matched to a struct field
+type FieldNaming int
+
+// field naming strategies
+const (
+	// NamingGo matches Go field name only
+	NamingGo FieldNaming = iota
+	// NamingTag matches name in cmprule struct tag first, then Go field name
+	NamingTag
+	// NamingTagJSON matches name in cmprule struct tag first, then name in json struct tag, then Go field name
+	NamingTagJSON
+)
+
+// fieldResolver resolves a field path in a struct
+type fieldResolver struct {
+	naming     FieldNaming
+	ignoreCase bool
+}
+
+// quantifiers of a wildcard selector
+const (
+	quantAll = iota
+	quantAny
+	quantNone
+)
+
+var quantNames = map[string]int{
+	"all":  quantAll,
+	"any":  quantAny,
+	"none": quantNone,
+}
+
+// pathSelector is a "[...]" following a field name, or a quantifier around it
+type pathSelector struct {
+	wildcard bool
+	quant    int
+	key      string
+}
+
+// pathSegment is one level of a field path, like "Ports[3]" or "any(Ports)"
+type pathSegment struct {
+	raw       string
+	name      string
+	selectors []pathSelector
+}
+
+// use "." as seperator, like "aaa.bbb.ccc";
+// "." within brackets, parentheses or double-quoted string is not a seperator, like `aaa["b.c"].ddd`
+func defaultParseNestedStructFunc(fieldName string) []string {
+	var r []string
+	depth := 0
+	inQuote := false
+	start := 0
+	for i := 0; i < len(fieldName); i++ {
+		c := fieldName[i]
+		switch {
+		case inQuote:
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				inQuote = false
+			}
+		case c == '"':
+			inQuote = true
+		case c == '[' || c == '(':
+			depth++
+		case c == ']' || c == ')':
+			depth--
+		case c == '.' && depth == 0:
+			r = append(r, fieldName[start:i])
+			start = i + 1
+		}
+	}
+	return append(r, fieldName[start:])
+}
+
+// parseFieldPath parses each name in nameList into a pathSegment
+func parseFieldPath(nameList []string) ([]pathSegment, error) {
+	var r []pathSegment
+	for _, name := range nameList {
+		seg, err := parsePathSegment(name)
+		if err != nil {
+			return nil, &ParseError{Token: name, Column: -1, Msg: "invalid field name", Err: err}
+		}
+		r = append(r, seg)
+	}
+	return r, nil
+}
+
+// format: "name", "name[index]", "name[*]", "quantifier(name)"; index could be repeated like "name[1][2]";
+// index could be a map key, quoted key like `name["a.b"]` could contain any character
+func parsePathSegment(raw string) (pathSegment, error) {
+	s := strings.TrimSpace(raw)
+	seg := pathSegment{raw: s}
+	if s == "" {
+		return seg, fmt.Errorf("empty field name")
+	}
+	if i := strings.Index(s, "("); i > 0 && s[len(s)-1] == ')' {
+		quant, ok := quantNames[s[:i]]
+		if !ok {
+			return seg, fmt.Errorf("unknown quantifier %v in field name %v", s[:i], s)
+		}
+		//empty name like "any()" refers to the input itself
+		var inner pathSegment
+		if innerName := strings.TrimSpace(s[i+1 : len(s)-1]); innerName != "" {
+			var err error
+			inner, err = parsePathSegment(innerName)
+			if err != nil {
+				return seg, err
+			}
+		}
+		seg.name = inner.name
+		seg.selectors = append(inner.selectors, pathSelector{wildcard: true, quant: quant})
+		return seg, nil
+	}
+	i := strings.Index(s, "[")
+	if i < 0 {
+		seg.name = s
+		return seg, nil
+	}
+	seg.name = strings.TrimSpace(s[:i])
+	rest := s[i:]
+	for rest != "" {
+		if rest[0] != '[' {
+			return seg, fmt.Errorf("invalid field name %v", s)
+		}
+		inner := strings.TrimSpace(rest[1:])
+		if inner != "" && inner[0] == '"' {
+			//quoted map key, could contain "]"
+			end := closingQuote(inner)
+			if end < 0 {
+				return seg, fmt.Errorf("unclosed quote in field name %v", s)
+			}
+			key, err := strconv.Unquote(inner[:end+1])
+			if err != nil {
+				return seg, fmt.Errorf("invalid quoted key in field name %v, %w", s, err)
+			}
+			inner = strings.TrimSpace(inner[end+1:])
+			if inner == "" || inner[0] != ']' {
+				return seg, fmt.Errorf("missing ] in field name %v", s)
+			}
+			seg.selectors = append(seg.selectors, pathSelector{key: key})
+			rest = strings.TrimSpace(inner[1:])
+			continue
+		}
+		end := strings.Index(rest, "]")
+		if end < 0 {
+			return seg, fmt.Errorf("missing ] in field name %v", s)
+		}
+		key := strings.TrimSpace(rest[1:end])
+		switch {
+		case key == "*":
+			seg.selectors = append(seg.selectors, pathSelector{wildcard: true, quant: quantAll})
+		case key == "":
+			return seg, fmt.Errorf("empty index in field name %v", s)
+		default:
+			seg.selectors = append(seg.selectors, pathSelector{key: key})
+		}
+		rest = strings.TrimSpace(rest[end+1:])
+	}
+	return seg, nil
+}
+
+// closingQuote returns index of the '"' closing the double-quoted string at the start of s, -1 if not found
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// deref returns the value v points to, return an error if v is a nil pointer
+func deref(v interface{}) (interface{}, error) {
+	for v != nil && reflect.TypeOf(v).Kind() == reflect.Ptr {
+		if reflect.ValueOf(v).IsNil() {
+			return nil, fmt.Errorf("%v is %w", reflect.TypeOf(v), ErrNilPoint)
+		}
+		v = reflect.ValueOf(v).Elem().Interface()
+	}
+	if v == nil {
+		return nil, fmt.Errorf("nil interface is %w", ErrNilPoint)
+	}
+	return v, nil
+}
+
+// visitFunc is called with the resolved name and value of a field
+type visitFunc func(name string, v interface{}) (bool, error)
+
+// walkField resolves the field specified by path in input, and calls visit with the field value;
+// prefix is the resolved name of input;
+// for a wildcard selector, the rest of path is resolved for every element,
+// and the results of visit are combined by the quantifier
+func (fr fieldResolver) walkField(input interface{}, prefix string, path []pathSegment, visit visitFunc) (bool, error) {
+	if len(path) == 0 {
+		v, err := deref(input)
+		if err != nil {
+			return false, err
+		}
+		return visit(prefix, v)
+	}
+	seg := path[0]
+	cur := input
+	name := prefix
+	if seg.name != "" {
+		v, err := deref(cur)
+		if err != nil {
+			return false, err
+		}
+		switch reflect.TypeOf(v).Kind() {
+		case reflect.Map:
+			cur, err = getMapValue(v, seg.name)
+		default:
+			cur, err = fr.getStructField(v, seg.name)
+		}
+		if err != nil {
+			return false, err
+		}
+		if name != "" {
+			name += "."
+		}
+		name += seg.name
+	}
+	for i, sel := range seg.selectors {
+		v, err := deref(cur)
+		if err != nil {
+			return false, err
+		}
+		val := reflect.ValueOf(v)
+		if sel.wildcard {
+			if val.Kind() != reflect.Slice && val.Kind() != reflect.Array && val.Kind() != reflect.Map {
+				return false, notContainerError(seg.raw, val.Type())
+			}
+			rest := append([]pathSegment{{raw: seg.raw, selectors: seg.selectors[i+1:]}}, path[1:]...)
+			return fr.walkElements(val, name, sel.quant, rest, visit)
+		}
+		switch val.Kind() {
+		case reflect.Slice, reflect.Array:
+			index, err := strconv.Atoi(sel.key)
+			if err != nil {
+				return false, &ParseError{Token: sel.key, Column: -1, Msg: fmt.Sprintf("invalid index %v in %v", sel.key, seg.raw)}
+			}
+			if index < 0 || index >= val.Len() {
+				return false, &FieldNotFoundError{Token: sel.key, Column: -1, Type: val.Type().String(),
+					Msg: fmt.Sprintf("index %v out of range in %v, length is %d", index, seg.raw, val.Len())}
+			}
+			cur = val.Index(index).Interface()
+			name += "[" + sel.key + "]"
+		case reflect.Map:
+			cur, err = getMapValue(v, sel.key)
+			if err != nil {
+				return false, err
+			}
+			if k := val.Type().Key().Kind(); k == reflect.String || k == reflect.Interface {
+				name += "[" + strconv.Quote(sel.key) + "]"
+			} else {
+				name += "[" + sel.key + "]"
+			}
+		default:
+			return false, notContainerError(seg.raw, val.Type())
+		}
+	}
+	return fr.walkField(cur, name, path[1:], visit)
+}
+
+// walkElements resolves path in every element of val, which is a slice, array or map,
+// and combines the results by quant; elements of a map are visited in the order of sorted keys
+func (fr fieldResolver) walkElements(val reflect.Value, prefix string, quant int, path []pathSegment, visit visitFunc) (bool, error) {
+	var elements []reflect.Value
+	var names []string
+	if val.Kind() == reflect.Map {
+		keys := val.MapKeys()
+		for _, k := range keys {
+			names = append(names, formatMapKey(k))
+		}
+		sort.Sort(mapKeySorter{keys: keys, names: names})
+		for _, k := range keys {
+			elements = append(elements, val.MapIndex(k))
+		}
+	} else {
+		for i := 0; i < val.Len(); i++ {
+			elements = append(elements, val.Index(i))
+			names = append(names, strconv.Itoa(i))
+		}
+	}
+	for i, e := range elements {
+		r, err := fr.walkField(e.Interface(), prefix+"["+names[i]+"]", path, visit)
+		if err != nil {
+			return false, err
+		}
+		switch quant {
+		case quantAll:
+			if !r {
+				return false, nil
+			}
+		case quantAny:
+			if r {
+				return true, nil
+			}
+		case quantNone:
+			if r {
+				return false, nil
+			}
+		}
+	}
+	return quant != quantAny, nil
+}
+
+// formatMapKey returns key as it is written in a field name, key of string kind is double-quoted
+func formatMapKey(key reflect.Value) string {
+	if key.Kind() == reflect.Interface {
+		key = key.Elem()
+	}
+	if key.Kind() == reflect.String {
+		return strconv.Quote(key.String())
+	}
+	return fmt.Sprint(key.Interface())
+}
+
+// mapKeySorter sorts map keys by their formatted names
+type mapKeySorter struct {
+	keys  []reflect.Value
+	names []string
+}
+
+func (s mapKeySorter) Len() int           { return len(s.keys) }
+func (s mapKeySorter) Less(i, j int) bool { return s.names[i] < s.names[j] }
+func (s mapKeySorter) Swap(i, j int) {
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+	s.names[i], s.names[j] = s.names[j], s.names[i]
+}
+
+// getStructField returns the field fname of inputStruct
+func (fr fieldResolver) getStructField(inputStruct interface{}, fname string) (interface{}, error) {
+	currentType := reflect.TypeOf(inputStruct)
+	f, err := fr.lookupField(currentType, fname)
+	if err != nil {
+		return nil, err
+	}
+	return reflect.ValueOf(inputStruct).FieldByIndex(f.Index).Interface(), nil
+}
+
+// lookupField returns the exported field fname of struct type t
+func (fr fieldResolver) lookupField(t reflect.Type, fname string) (reflect.StructField, error) {
+	if t.Kind() != reflect.Struct {
+		return reflect.StructField{}, &TypeMismatchError{Token: fname, Column: -1, Type: t.String(),
+			Msg: fmt.Sprintf("%v is not a struct", t)}
+	}
+	f, ok := fr.findField(t, fname)
+	if !ok {
+		return f, &FieldNotFoundError{Token: fname, Column: -1, Type: t.String(),
+			Msg: fmt.Sprintf("field %v doesn't exist in %v", fname, t)}
+	}
+	if f.PkgPath != "" {
+		return f, &FieldNotFoundError{Token: fname, Column: -1, Type: t.String(),
+			Msg: fmt.Sprintf("field %v of %v is not exported", fname, t)}
+	}
+	return f, nil
+}
+
+// notContainerError returns a TypeMismatchError for field name of type t, which is not a slice, array or map
+func notContainerError(name string, t reflect.Type) error {
+	return &TypeMismatchError{Token: name, Column: -1, Type: t.String(),
+		Msg: fmt.Sprintf("%v is not a slice, array or map", name)}
+}
+
+// invalidKeyError returns a ParseError for a key can't be parsed into key type of map type t
+func invalidKeyError(key string, t reflect.Type, err error) error {
+	return &ParseError{Token: key, Column: -1, Msg: fmt.Sprintf("invalid key %v for %v", key, t), Err: err}
+}
+
+// findField returns the field of struct type t matches name according to fr.naming;
+// a name in struct tag takes precedence over Go field name
+func (fr fieldResolver) findField(t reflect.Type, name string) (reflect.StructField, bool) {
+	var tagKeys []string
+	switch fr.naming {
+	case NamingTag:
+		tagKeys = []string{tagName}
+	case NamingTagJSON:
+		tagKeys = []string{tagName, "json"}
+	}
+	for _, key := range tagKeys {
+		if f, ok := fr.findTaggedField(t, key, name, nil); ok {
+			return f, true
+		}
+	}
+	if fr.ignoreCase {
+		return t.FieldByNameFunc(func(n string) bool {
+			return strings.EqualFold(n, name)
+		})
+	}
+	return t.FieldByName(name)
+}
+
+// findTaggedField returns the exported field of struct type t, whose name in tag key matches name;
+// fields of embedded struct are also searched, index is the index of t within the outmost struct
+func (fr fieldResolver) findTaggedField(t reflect.Type, key, name string, index []int) (reflect.StructField, bool) {
+	var embedded []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		f.Index = append(append([]int{}, index...), i)
+		tagged := tagFieldName(f.Tag.Get(key))
+		if tagged == "" {
+			if f.Anonymous && f.Type.Kind() == reflect.Struct {
+				embedded = append(embedded, f)
+			}
+			continue
+		}
+		if f.PkgPath != "" || tagged == "-" {
+			continue
+		}
+		if tagged == name || (fr.ignoreCase && strings.EqualFold(tagged, name)) {
+			return f, true
+		}
+	}
+	for _, f := range embedded {
+		if r, ok := fr.findTaggedField(f.Type, key, name, f.Index); ok {
+			return r, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// tagFieldName returns the name part of a struct tag value like "name,option"
+func tagFieldName(tag string) string {
+	if i := strings.Index(tag, ","); i >= 0 {
+		return tag[:i]
+	}
+	return tag
+}
+
+// tagOption returns the value of option key in a struct tag value like "name,key=value"
+func tagOption(tag, key string) string {
+	for _, opt := range strings.Split(tag, ",")[1:] {
+		if strings.HasPrefix(opt, key+"=") {
+			return strings.TrimPrefix(opt, key+"=")
+		}
+	}
+	return ""
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// getMapValue returns the value of inputMap for key, which is parsed according to the key type of inputMap
+func getMapValue(inputMap interface{}, key string) (interface{}, error) {
+	mapVal := reflect.ValueOf(inputMap)
+	keyType := mapVal.Type().Key()
+	k, err := parseMapKey(keyType, key)
+	if err != nil {
+		return nil, invalidKeyError(key, mapVal.Type(), err)
+	}
+	v := mapVal.MapIndex(k)
+	if !v.IsValid() {
+		return nil, &FieldNotFoundError{Token: key, Column: -1, Type: mapVal.Type().String(),
+			Msg: fmt.Sprintf("key %v doesn't exist in %v", key, mapVal.Type())}
+	}
+	return v.Interface(), nil
+}
+
+// parseMapKey parses key into a value of keyType,
+// supported key types are string, integer, float, bool, interface{} (as string) and types implement encoding.TextUnmarshaler
+func parseMapKey(keyType reflect.Type, key string) (reflect.Value, error) {
+	k := reflect.New(keyType).Elem()
+	if reflect.PtrTo(keyType).Implements(textUnmarshalerType) {
+		err := k.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(key))
+		return k, err
+	}
+	switch keyType.Kind() {
+	case reflect.String:
+		k.SetString(key)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(key, 0, keyType.Bits())
+		if err != nil {
+			return k, err
+		}
+		k.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(key, 0, keyType.Bits())
+		if err != nil {
+			return k, err
+		}
+		k.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(key, keyType.Bits())
+		if err != nil {
+			return k, err
+		}
+		k.SetFloat(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(key)
+		if err != nil {
+			return k, err
+		}
+		k.SetBool(b)
+	case reflect.Interface:
+		if keyType.NumMethod() != 0 {
+			return k, fmt.Errorf("unsupported key type %v", keyType)
+		}
+		k.Set(reflect.ValueOf(key))
+	default:
+		return k, fmt.Errorf("unsupported key type %v", keyType)
+	}
+	return k, nil
+}
@@ -0,0 +1,267 @@
+// cmprule_test
+package cmprule
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testPort struct {
+	State  string
+	Errors int
+	Speed  uint
+	Queues []int
+}
+
+type testStructSlice struct {
+	Ports      []testPort
+	PointPorts []*testPort
+	Array1     [3]int
+	Matrix     [][]int
+	Empty      []testPort
+	Num1       int
+	private    []int
+}
+
+var test_struct_slice = testStructSlice{
+	Ports: []testPort{
+		{State: "up", Errors: 0, Speed: 1000, Queues: []int{1, 2}},
+		{State: "up", Errors: 3, Speed: 100, Queues: []int{3}},
+		{State: "up", Errors: 0, Speed: 10000},
+		{State: "up", Errors: 0, Speed: 1000, Queues: []int{4, 5, 6}},
+	},
+	PointPorts: []*testPort{{State: "up"}, nil},
+	Array1:     [3]int{1, 2, 3},
+	Matrix:     [][]int{{1, 2}, {3, 4}},
+	Num1:       100,
+	private:    []int{1},
+}
+
+var test_list_slice = []testResult{
+	{`Ports[*].State:same:"up"`, true, false},
+	{`all(Ports).State:same:"up"`, true, false},
+	{`any(Ports).Errors:>:0`, true, false},
+	{`none(Ports).Errors:>:0`, false, false},
+	{`none(Ports).Errors:>:10`, true, false},
+	{`Ports[*].Errors:==:0`, false, false},
+	{`Ports[3].Speed:>=:1000`, true, false},
+	{`Ports[ 1 ].Speed:>=:1000`, false, false},
+	{`Ports[4].Speed:>=:1000`, false, true},
+	{`Ports[-1].Speed:>=:1000`, false, true},
+	{`Ports[a].Speed:>=:1000`, false, true},
+	{`Ports[].Speed:>=:1000`, false, true},
+	{`Ports[1.Speed:>=:1000`, false, true},
+	{`Ports[*].Queues[*]:>:0`, true, false},
+	{`any(Ports).Queues[*]:>:3`, true, false},
+	{`any(Ports[*].Queues):==:5`, false, true},
+	{`Ports[0].Queues[1]:==:2`, true, false},
+	{`Array1[*]:in:1 3`, true, false},
+	{`Array1[2]:==:3`, true, false},
+	{`any(Array1):==:2`, true, false},
+	{`Matrix[1][0]:==:3`, true, false},
+	{`any(Matrix[*]):==:1`, false, false},
+	{`any(Matrix[*]):>:1`, true, false},
+	{`Matrix[*][*]:<:5`, true, false},
+	{`Empty[*].Errors:>:0`, true, false},
+	{`any(Empty).Errors:>:0`, false, false},
+	{`none(Empty).Errors:>:0`, true, false},
+	{`PointPorts[0].State:same:"up"`, true, false},
+	{`PointPorts[*].State:same:"up"`, false, true},
+	{`Num1[0]:==:100`, false, true},
+	{`some(Ports).Errors:>:0`, false, true},
+	{`Ports:==:0`, false, true},
+	{`private[0]:==:1`, false, true},
+}
+
+func TestSliceField(t *testing.T) {
+	tableTest(test_struct_slice, test_list_slice, t)
+}
+
+type testKey struct {
+	a, b string
+}
+
+func (k *testKey) UnmarshalText(text []byte) error {
+	fields := strings.SplitN(string(text), "/", 2)
+	if len(fields) != 2 {
+		return fmt.Errorf("invalid key %v", string(text))
+	}
+	k.a, k.b = fields[0], fields[1]
+	return nil
+}
+
+type testStructMap struct {
+	Sessions    map[string]testPort
+	Counters    map[string]uint64
+	IntMap      map[int]string
+	UintMap     map[uint8]int
+	FloatMap    map[float64]int
+	BoolMap     map[bool]int
+	AnyMap      map[interface{}]int
+	TextMap     map[testKey]int
+	PointMap    *map[string]int
+	NestedMap   map[string]map[string]int
+	SliceMap    map[string][]int
+	MapSlice    []map[string]int
+	Unsupported map[[2]int]int
+}
+
+var test_map_point = map[string]int{"a": 1}
+
+var test_struct_map = testStructMap{
+	Sessions: map[string]testPort{
+		"peer1":   {State: "up", Errors: 0},
+		"peer2":   {State: "down", Errors: 3},
+		"peer.3]": {State: "up", Errors: 1},
+	},
+	Counters:    map[string]uint64{"rx_bytes": 1000, "tx_bytes": 2000},
+	IntMap:      map[int]string{-1: "minus", 3: "three"},
+	UintMap:     map[uint8]int{255: 1},
+	FloatMap:    map[float64]int{1.5: 1},
+	BoolMap:     map[bool]int{true: 1},
+	AnyMap:      map[interface{}]int{"a": 1},
+	TextMap:     map[testKey]int{{"x", "y"}: 1},
+	PointMap:    &test_map_point,
+	NestedMap:   map[string]map[string]int{"a": {"b": 2}},
+	SliceMap:    map[string][]int{"a": {1, 2}},
+	MapSlice:    []map[string]int{{"a": 1}, {"a": 2}},
+	Unsupported: map[[2]int]int{{1, 2}: 1},
+}
+
+var test_list_map = []testResult{
+	{`Sessions["peer1"].State:same:"up"`, true, false},
+	{`Sessions[peer2].State:same:"up"`, false, false},
+	{`Sessions.peer2.Errors:==:3`, true, false},
+	{`Sessions["peer.3]"].Errors:==:1`, true, false},
+	{`Sessions[ "peer1" ].Errors:==:0`, true, false},
+	{`Sessions["peer4"].State:same:"up"`, false, true},
+	{`Sessions["peer1].State:same:"up"`, false, true},
+	{`Sessions["peer1".State:same:"up"`, false, true},
+	{`Sessions[*].State:same:"up"`, false, false},
+	{`any(Sessions).State:same:"down"`, true, false},
+	{`none(Sessions).Errors:>:5`, true, false},
+	{`Counters.rx_bytes:==:1000`, true, false},
+	{`Counters["tx_bytes"]:>:1000`, true, false},
+	{`Counters[*]:>=:1000`, true, false},
+	{`IntMap[-1]:same:"minus"`, true, false},
+	{`IntMap[0x3]:same:"three"`, true, false},
+	{`IntMap[4]:same:"three"`, false, true},
+	{`IntMap[a]:same:"three"`, false, true},
+	{`UintMap[255]:==:1`, true, false},
+	{`UintMap[256]:==:1`, false, true},
+	{`FloatMap[1.5]:==:1`, true, false},
+	{`BoolMap[true]:==:1`, true, false},
+	{`AnyMap[a]:==:1`, true, false},
+	{`TextMap["x/y"]:==:1`, true, false},
+	{`TextMap[xy]:==:1`, false, true},
+	{`PointMap.a:==:1`, true, false},
+	{`NestedMap.a.b:==:2`, true, false},
+	{`NestedMap["a"]["b"]:==:2`, true, false},
+	{`NestedMap[*][*]:==:2`, true, false},
+	{`SliceMap.a[1]:==:2`, true, false},
+	{`any(SliceMap.a):==:2`, false, true},
+	{`any(SliceMap["a"]):==:2`, true, false},
+	{`MapSlice[*].a:>:0`, true, false},
+	{`Unsupported[1]:==:1`, false, true},
+}
+
+func TestMapField(t *testing.T) {
+	tableTest(test_struct_map, test_list_map, t)
+}
+
+type testTagEmbedded struct {
+	Drops int `json:"drops"`
+}
+
+type testStructTag struct {
+	testTagEmbedded
+	RxPkts  int    `cmprule:"rx_packets" json:"rxPkts"`
+	TxPkts  int    `json:"txPkts,omitempty"`
+	Name    string `cmprule:"name,option"`
+	Ignored int    `cmprule:"-" json:"-"`
+	Other   int    `json:"RxPkts"`
+	hidden  int    `cmprule:"hidden"`
+}
+
+func TestFieldNaming(t *testing.T) {
+	input := testStructTag{
+		testTagEmbedded: testTagEmbedded{Drops: 5},
+		RxPkts:          100, TxPkts: 200, Name: "n1", Ignored: 1, Other: 300, hidden: 1,
+	}
+	cases := []struct {
+		naming     FieldNaming
+		ignoreCase bool
+		list       []testResult
+	}{
+		{NamingGo, false, []testResult{
+			{"RxPkts:==:100", true, false},
+			{"rx_packets:==:100", false, true},
+			{"rxpkts:==:100", false, true},
+			{"Drops:==:5", true, false},
+		}},
+		{NamingTag, false, []testResult{
+			{"rx_packets:==:100", true, false},
+			{"RxPkts:==:100", true, false},
+			{"name:same:\"n1\"", true, false},
+			{"txPkts:==:200", false, true},
+			{"hidden:==:1", false, true},
+			{"Ignored:==:1", true, false},
+		}},
+		{NamingTagJSON, false, []testResult{
+			{"rx_packets:==:100", true, false},
+			{"rxPkts:==:100", true, false},
+			{"txPkts:==:200", true, false},
+			{"TxPkts:==:200", true, false},
+			{"drops:==:5", true, false},
+			{"RxPkts:==:300", true, false},
+			{"Rx_Packets:==:100", false, true},
+		}},
+		{NamingTagJSON, true, []testResult{
+			{"Rx_Packets:==:100", true, false},
+			{"TXPKTS:==:200", true, false},
+			{"other:==:300", true, false},
+			{"DROPS:==:5", true, false},
+		}},
+		{NamingGo, true, []testResult{
+			{"rxpkts:==:100", true, false},
+			{"rx_packets:==:100", false, true},
+		}},
+	}
+	for _, c := range cases {
+		cmp := NewDefaultCMPRule()
+		cmp.SetFieldNaming(c.naming, c.ignoreCase)
+		for _, tt := range c.list {
+			if err := cmp.ParseRule(tt.in); err != nil {
+				t.Fatal(err)
+			}
+			result, err := cmp.Compare(input)
+			t.Logf("naming %v ignore case %v, input: %v; result: %v, err: %v", c.naming, c.ignoreCase, tt.in, result, err)
+			if (err != nil) != tt.expect_err {
+				t.Fatalf("unexpected err: %v", err)
+			}
+			if result != tt.out_bool {
+				t.Fatalf("expect %v, got %v", tt.out_bool, result)
+			}
+		}
+	}
+}
+
+func TestParseNestedStruct(t *testing.T) {
+	cases := []struct {
+		in  string
+		out []string
+	}{
+		{"aa.bb.cc", []string{"aa", "bb", "cc"}},
+		{"aa[1].bb", []string{"aa[1]", "bb"}},
+		{`aa["b.c"].dd`, []string{`aa["b.c"]`, "dd"}},
+		{`any(aa.bb).cc`, []string{"any(aa.bb)", "cc"}},
+	}
+	for _, c := range cases {
+		r := defaultParseNestedStructFunc(c.in)
+		if !reflect.DeepEqual(r, c.out) {
+			t.Fatalf("input %v, expect %v, got %v", c.in, c.out, r)
+		}
+	}
+}
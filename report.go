@@ -0,0 +1,167 @@
+// Copyright 2020 Hu Jun. All rights reserved.
+// This project is licensed under the terms of the MIT license.
+// license that can be found in the LICENSE file.
+
+package cmprule
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ReportSuite is a named list of rule results written by report writers,
+// like the results of a RuleSet evaluated against one input
+type ReportSuite struct {
+	Name    string
+	Results []RuleResult
+}
+
+// EvaluateRules compares input against each of rules, and returns a RuleResult for each rule
+func EvaluateRules(input interface{}, rules ...*CMPRule) []RuleResult {
+	results := make([]RuleResult, len(rules))
+	for i, rule := range rules {
+		results[i] = newRuleResult(rule.String(), rule.CompareDetailed(input), Source{})
+	}
+	return results
+}
+
+// explanation returns the explanation of r like `Num1 = -120, expected > 100`
+func (r RuleResult) explanation() string {
+	if r.Detail == nil {
+		if r.Err != nil {
+			return r.Err.Error()
+		}
+		return ""
+	}
+	return r.Detail.String()
+}
+
+// describe returns a description of the rule with its source if it is known
+func (r RuleResult) describe() string {
+	if r.Source.IsZero() {
+		return r.Rule
+	}
+	return fmt.Sprintf("%v (%v)", r.Rule, r.Source)
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes suites as a JUnit XML report, with a testsuite for each of suites and a testcase for each rule;
+// a failed rule is reported as a failure with actual vs expected values as message,
+// a rule could not be evaluated is reported as an error.
+// the classname of a testcase is the source of the rule if it is loaded from a rule file, otherwise the suite name
+func WriteJUnit(w io.Writer, suites ...ReportSuite) error {
+	var report junitTestSuites
+	for _, s := range suites {
+		suite := junitTestSuite{Name: s.Name, Cases: []junitTestCase{}}
+		for _, r := range s.Results {
+			tc := junitTestCase{Name: r.Rule, ClassName: s.Name}
+			if !r.Source.IsZero() {
+				tc.ClassName = r.Source.String()
+			}
+			switch {
+			case r.Err != nil:
+				tc.Error = &junitMessage{Message: r.Err.Error(), Text: r.explanation()}
+				suite.Errors++
+			case !r.Result:
+				tc.Failure = &junitMessage{Message: r.explanation(), Text: r.describe()}
+				suite.Failures++
+			}
+			suite.Cases = append(suite.Cases, tc)
+		}
+		suite.Tests = len(suite.Cases)
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Errors += suite.Errors
+		report.Suites = append(report.Suites, suite)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// WriteTAP writes suites as a TAP version 13 report, with a test point for each rule of all suites;
+// the description of a test point is the rule, prefixed by the suite name if it is not empty,
+// the explanation of a failed rule is written as a YAML diagnostic block
+func WriteTAP(w io.Writer, suites ...ReportSuite) error {
+	total := 0
+	for _, s := range suites {
+		total += len(s.Results)
+	}
+	if _, err := fmt.Fprintf(w, "TAP version 13\n1..%d\n", total); err != nil {
+		return err
+	}
+	n := 0
+	for _, s := range suites {
+		for _, r := range s.Results {
+			n++
+			desc := tapEscape(r.Rule)
+			if s.Name != "" {
+				desc = tapEscape(s.Name) + ": " + desc
+			}
+			if r.Passed() {
+				if _, err := fmt.Fprintf(w, "ok %d - %v\n", n, desc); err != nil {
+					return err
+				}
+				continue
+			}
+			severity := "fail"
+			if r.Err != nil {
+				severity = "error"
+			}
+			_, err := fmt.Fprintf(w, "not ok %d - %v\n  ---\n  message: %q\n  severity: %v\n", n, desc, r.explanation(), severity)
+			if err != nil {
+				return err
+			}
+			if !r.Source.IsZero() {
+				if _, err := fmt.Fprintf(w, "  at: %q\n", r.Source.String()); err != nil {
+					return err
+				}
+			}
+			if _, err := io.WriteString(w, "  ...\n"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// tapEscape escapes '#' and '\' in a TAP description, and replaces newlines with spaces
+func tapEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "#", `\#`, "\n", " ", "\r", " ").Replace(s)
+}
@@ -0,0 +1,93 @@
+// cmprule_test
+package cmprule
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func testReportSuites(t *testing.T) []ReportSuite {
+	rs := NewRuleSet()
+	if err := rs.Load(strings.NewReader("Num1 : == : -120\nNum1 : > : 100\nNum2 : == : 1\n"), "test.rules"); err != nil {
+		t.Fatal(err)
+	}
+	rules := make([]*CMPRule, 2)
+	for i, text := range []string{`Str1 : contain : "#1"`, `Str1 : same : "a"`} {
+		rules[i] = NewDefaultCMPRule()
+		if err := rules[i].ParseRule(text); err != nil {
+			t.Fatal(err)
+		}
+	}
+	input := testStruct{Num1: -120, Str1: "test#1"}
+	return []ReportSuite{
+		{Name: "set", Results: rs.Evaluate(input)},
+		{Name: "", Results: EvaluateRules(input, rules...)},
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, testReportSuites(t)...); err != nil {
+		t.Fatal(err)
+	}
+	t.Log(buf.String())
+	var report junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Tests != 5 || report.Failures != 2 || report.Errors != 1 || len(report.Suites) != 2 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	cases := report.Suites[0].Cases
+	if cases[0].Failure != nil || cases[0].Error != nil || cases[0].ClassName != "test.rules:1" {
+		t.Fatalf("unexpected testcase %+v", cases[0])
+	}
+	if cases[1].Failure == nil || cases[1].Failure.Message != "Num1 = -120, expected > 100" || cases[1].Failure.Text != "Num1 : > : 100 (test.rules:2)" {
+		t.Fatalf("unexpected testcase %+v", cases[1])
+	}
+	if cases[2].Error == nil || !strings.Contains(cases[2].Error.Message, "Num2 doesn't exist") {
+		t.Fatalf("unexpected testcase %+v", cases[2])
+	}
+	if c := report.Suites[1].Cases[1]; c.Failure == nil || c.Name != `Str1 : same : "a"` || c.Failure.Message != `Str1 = "test#1", expected same "a"` {
+		t.Fatalf("unexpected testcase %+v", c)
+	}
+	buf.Reset()
+	if err := WriteJUnit(&buf); err != nil || !strings.Contains(buf.String(), `<testsuites tests="0" failures="0" errors="0"></testsuites>`) {
+		t.Fatalf("unexpected empty report %v, %v", buf.String(), err)
+	}
+}
+
+func TestWriteTAP(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteTAP(&buf, testReportSuites(t)...); err != nil {
+		t.Fatal(err)
+	}
+	t.Log(buf.String())
+	expected := `TAP version 13
+1..5
+ok 1 - set: Num1 : == : -120
+not ok 2 - set: Num1 : > : 100
+  ---
+  message: "Num1 = -120, expected > 100"
+  severity: fail
+  at: "test.rules:2"
+  ...
+not ok 3 - set: Num2 : == : 1
+  ---
+  message: "Num2: field Num2 doesn't exist in cmprule.testStruct"
+  severity: error
+  at: "test.rules:3"
+  ...
+ok 4 - Str1 : contain : "\#1"
+not ok 5 - Str1 : same : "a"
+  ---
+  message: "Str1 = \"test#1\", expected same \"a\""
+  severity: fail
+  ...
+`
+	if buf.String() != expected {
+		t.Fatalf("expect\n%v\ngot\n%v", expected, buf.String())
+	}
+}
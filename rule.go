@@ -0,0 +1,61 @@
+// Copyright 2020 Hu Jun. All rights reserved.
+// This project is licensed under the terms of the MIT license.
+// license that can be found in the LICENSE file.
+
+package cmprule
+
+// Rule is a compiled rule, it can't be changed after compiled,
+// and it is safe to be used by multiple goroutines concurrently
+type Rule struct {
+	cmprule *CMPRule
+}
+
+// Compile parses rawrule into a Rule, by using a CMPRule instance created by NewDefaultCMPRule,
+// see package doc for the default format of the rawrule string
+func Compile(rawrule string) (*Rule, error) {
+	return CompileWithFunc(rawrule, NewDefaultCMPRule)
+}
+
+// CompileWithFunc is same as Compile, except rawrule is parsed by a CMPRule instance created by newRule,
+// this could be used to customize the rule format, see CMPRule.SetxxxFunc()
+func CompileWithFunc(rawrule string, newRule func() *CMPRule) (*Rule, error) {
+	cmprule := newRule()
+	if err := cmprule.ParseRule(rawrule); err != nil {
+		return nil, err
+	}
+	return &Rule{cmprule: cmprule}, nil
+}
+
+// MustCompile is like Compile but panics if rawrule can't be parsed
+func MustCompile(rawrule string) *Rule {
+	r, err := Compile(rawrule)
+	if err != nil {
+		panic("cmprule: Compile(" + rawrule + "): " + err.Error())
+	}
+	return r
+}
+
+// Compare input against the rule, see CMPRule.Compare
+func (r *Rule) Compare(input interface{}) (bool, error) {
+	return r.cmprule.Compare(input)
+}
+
+// CompareDetailed compares input against the rule and returns a detailed result, see CMPRule.CompareDetailed
+func (r *Rule) CompareDetailed(input interface{}) *CompareResult {
+	return r.cmprule.CompareDetailed(input)
+}
+
+// Explain compares input against the rule and returns a human-readable explanation, see CMPRule.Explain
+func (r *Rule) Explain(input interface{}) string {
+	return r.cmprule.Explain(input)
+}
+
+// CompareJSON decodes data as a JSON document and compares it against the rule, see CMPRule.CompareJSON
+func (r *Rule) CompareJSON(data []byte) (bool, error) {
+	return r.cmprule.CompareJSON(data)
+}
+
+// String returns the raw rule text
+func (r *Rule) String() string {
+	return r.cmprule.String()
+}
@@ -0,0 +1,79 @@
+// cmprule_test
+package cmprule
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCompile(t *testing.T) {
+	input := testStruct{Num1: -120, Str1: "test1", Duration1: 10 * time.Second}
+	list := []testResult{
+		{"Num1:==:-120", true, false},
+		{"Num1:>:100", false, false},
+		{`Str1:contain:"test"`, true, false},
+		{"Duration1:in:5s 20s", true, false},
+		{"Num_notexist:==:100", false, true},
+	}
+	for _, c := range list {
+		r, err := Compile(c.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.String() != c.in {
+			t.Fatalf("expect rule %v, got %v", c.in, r.String())
+		}
+		result, err := r.Compare(input)
+		if (err != nil) != c.expect_err {
+			t.Fatalf("rule %v unexpected err: %v", c.in, err)
+		}
+		if result != c.out_bool {
+			t.Fatalf("rule %v expect %v, got %v", c.in, c.out_bool, result)
+		}
+	}
+	if _, err := Compile("Num1:in:100"); err == nil {
+		t.Fatal("expect parse error")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expect MustCompile to panic")
+		}
+	}()
+	MustCompile("Num1:in:100")
+}
+
+// TestCompileConcurrent compares the same rule against different types from multiple goroutines,
+// run with -race to detect data race
+func TestCompileConcurrent(t *testing.T) {
+	type durationStruct struct {
+		Num1 time.Duration
+	}
+	// "0" is a valid value for both int and time.Duration
+	r := MustCompile("Num1:!=:0")
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				var input interface{} = test_struct
+				expected := true
+				if (i+j)%2 == 0 {
+					input = durationStruct{}
+					expected = false
+				}
+				result, err := r.Compare(input)
+				if err != nil {
+					t.Errorf("unexpected err: %v", err)
+					return
+				}
+				if result != expected {
+					t.Errorf("expect %v, got %v", expected, result)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
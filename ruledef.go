@@ -0,0 +1,270 @@
+// Copyright 2020 Hu Jun. All rights reserved.
+// This project is licensed under the terms of the MIT license.
+// license that can be found in the LICENSE file.
+
+package cmprule
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// keys of a range value in a RuleDef
+const (
+	ruleDefMin = "min"
+	ruleDefMax = "max"
+)
+
+// RuleDef is a structured definition of a rule, as an alternative of the text form "field : op : value";
+// Value could be:
+//
+//	- a single value, like 100, "5s" or true
+//	- a list of values, like [1, 2, 3] or ["Passed", "Failed"]
+//	- a range, like {"min": 1, "max": 10} or a list of 2 values, for operator in and notin
+//	- a list of a value and a tolerance, like [12.5, "±0.01"] or [12.5, "1%"], for approximate compare operators
+//
+// strings in Value are used as they are, no quoting or escaping is needed;
+// a string value of a string operator can't end with '\', since it can't be expressed in the text form
+type RuleDef struct {
+	Field string      `json:"field"`
+	Op    string      `json:"op"`
+	Value interface{} `json:"value"`
+}
+
+// String returns the rule in the default rule format, like `Str1 : same : "a" "b"`
+func (def RuleDef) String() string {
+	values, err := ruleDefValueStrings(def.Value)
+	if err != nil {
+		return fmt.Sprintf("%v : %v : %v", def.Field, def.Op, def.Value)
+	}
+	if isStrOp(def.Op) {
+		for i, v := range values {
+			values[i] = `"` + strings.ReplaceAll(v, `"`, `\"`) + `"`
+		}
+	}
+	return fmt.Sprintf("%v : %v : %v", def.Field, def.Op, strings.Join(values, " "))
+}
+
+// ruleDefValueStrings returns the list of values in v, a range is returned as min and max
+func ruleDefValueStrings(v interface{}) ([]string, error) {
+	switch val := v.(type) {
+	case nil:
+		return nil, fmt.Errorf("missing value")
+	case []string:
+		return append([]string{}, val...), nil
+	case []interface{}:
+		var r []string
+		for _, e := range val {
+			s, err := ruleDefScalarString(e)
+			if err != nil {
+				return nil, err
+			}
+			r = append(r, s)
+		}
+		return r, nil
+	case map[string]interface{}:
+		if len(val) != 2 || val[ruleDefMin] == nil || val[ruleDefMax] == nil {
+			return nil, fmt.Errorf("a range must have only %v and %v", ruleDefMin, ruleDefMax)
+		}
+		min, err := ruleDefScalarString(val[ruleDefMin])
+		if err != nil {
+			return nil, err
+		}
+		max, err := ruleDefScalarString(val[ruleDefMax])
+		if err != nil {
+			return nil, err
+		}
+		return []string{min, max}, nil
+	default:
+		s, err := ruleDefScalarString(v)
+		if err != nil {
+			return nil, err
+		}
+		return []string{s}, nil
+	}
+}
+
+// ruleDefScalarString returns v as a string, v must be a string, a number or a bool
+func ruleDefScalarString(v interface{}) (string, error) {
+	switch val := v.(type) {
+	case string:
+		return val, nil
+	case json.Number:
+		return val.String(), nil
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64), nil
+	case float32:
+		return strconv.FormatFloat(float64(val), 'f', -1, 32), nil
+	case bool:
+		return strconv.FormatBool(val), nil
+	}
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return fmt.Sprint(v), nil
+	}
+	return "", fmt.Errorf("invalid value %v, must be a string, number or bool", v)
+}
+
+// ParseRuleDef gets a rule from a structured definition, it is same as ParseRule with the text form of def,
+// except the values are taken from def.Value directly, instead of being parsed by the parse functions of values;
+// the field name is still parsed by the function set by SetParseFieldNameFunc
+func (cmprule *CMPRule) ParseRuleDef(def RuleDef) error {
+	cmprule.rawRule = def.String()
+	cmprule.ClearPreparedInt64Value()
+	cmprule.ruleFieldName, cmprule.ruleOp = strings.TrimSpace(def.Field), strings.TrimSpace(def.Op)
+	values, err := ruleDefValueStrings(def.Value)
+	if err != nil {
+		return &ParseError{Rule: cmprule.rawRule, Token: fmt.Sprint(def.Value), Column: -1, Msg: "invalid value", Err: err}
+	}
+	cmprule.ruleVal = strings.Join(values, " ")
+	count := func(n int) error {
+		if len(values) != n {
+			return fmt.Errorf("expect %d value(s), got %d", n, len(values))
+		}
+		return nil
+	}
+	switch {
+	case detectType(cmprule.ruleOp) == valueSingle:
+		err = count(1)
+	case detectType(cmprule.ruleOp) == valueRange:
+		if err = count(2); err == nil {
+			cmprule.numMinStr, cmprule.numMaxStr = values[0], values[1]
+		}
+	case detectType(cmprule.ruleOp) == valueList:
+		cmprule.numListStr = values
+	case isStrOp(cmprule.ruleOp):
+		cmprule.strList = values
+		for _, v := range values {
+			if strings.HasSuffix(v, `\`) {
+				err = fmt.Errorf("string value %v ends with '\\', which can't be expressed in the text form", v)
+				break
+			}
+		}
+		if err != nil {
+			break
+		}
+		switch cmprule.ruleOp {
+		case opStrMatch, opStrNotMatch, opStrMatchI, opStrNotMatchI:
+			err = cmprule.compileRegexpList()
+		case opStrLike, opStrNotLike:
+			err = cmprule.checkGlobList()
+		}
+	case isApproxOp(cmprule.ruleOp):
+		if err = count(2); err == nil {
+			cmprule.approxValStr, cmprule.approxTolStr = values[0], values[1]
+			err = cmprule.parseApprox()
+		}
+	case cmprule.ruleOp == opIPWithin || cmprule.ruleOp == opIPNotWithin:
+		cmprule.ipNetList, err = cmprule.parseIPNetListFunc(cmprule.ruleVal)
+	case isIPPropertyOp(cmprule.ruleOp):
+		err = count(1)
+	default:
+		return cmprule.opError("")
+	}
+	if err == nil && len(values) == 0 {
+		err = fmt.Errorf("list is empty")
+	}
+	if err == nil && !isStrOp(cmprule.ruleOp) {
+		//strings of string operators are used as they are
+		err = cmprule.checkFieldRefOp(values)
+	}
+	if err == nil {
+		err = cmprule.parseFieldRef()
+	}
+	if err != nil {
+		return cmprule.valueError(cmprule.ruleVal, "invalid value", err)
+	}
+	return cmprule.parseField()
+}
+
+// RuleDef returns the structured definition of the parsed rule, String() of the returned RuleDef is the text form of the rule
+func (cmprule *CMPRule) RuleDef() RuleDef {
+	def := RuleDef{Field: cmprule.ruleFieldName, Op: cmprule.ruleOp}
+	switch {
+	case detectType(cmprule.ruleOp) == valueRange:
+		def.Value = map[string]interface{}{ruleDefMin: cmprule.numMinStr, ruleDefMax: cmprule.numMaxStr}
+	case detectType(cmprule.ruleOp) == valueSingle:
+		def.Value = cmprule.ruleVal
+	default:
+		var list []interface{}
+		for _, v := range cmprule.expectedValues() {
+			list = append(list, v)
+		}
+		def.Value = list
+	}
+	return def
+}
+
+// ParseRuleDefsJSON decodes data as a JSON array of rule definitions, like
+// [{"field": "Num1", "op": ">", "value": 100}, {"field": "Str1", "op": "same", "value": ["a", "b"]}]
+func ParseRuleDefsJSON(data []byte) ([]RuleDef, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	dec.DisallowUnknownFields()
+	var defs []RuleDef
+	if err := dec.Decode(&defs); err != nil {
+		return nil, fmt.Errorf("failed to decode rule definitions, %w", err)
+	}
+	return defs, nil
+}
+
+// ParseRuleDefsYAML decodes data as a YAML sequence of rule definitions, see package doc for the supported subset of YAML
+func ParseRuleDefsYAML(data []byte) ([]RuleDef, error) {
+	doc, err := decodeYAML(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode rule definitions, %w", err)
+	}
+	if doc == nil {
+		return nil, nil
+	}
+	list, ok := doc.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("rule definitions must be a sequence")
+	}
+	var defs []RuleDef
+	for i, item := range list {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("rule definition %d is not a mapping", i)
+		}
+		var def RuleDef
+		for k, v := range m {
+			switch k {
+			case "field", "op":
+				s, ok := v.(string)
+				if !ok {
+					return nil, fmt.Errorf("%v of rule definition %d is not a string", k, i)
+				}
+				if k == "field" {
+					def.Field = s
+				} else {
+					def.Op = s
+				}
+			case "value":
+				def.Value = v
+			default:
+				return nil, fmt.Errorf("unknown key %v in rule definition %d", k, i)
+			}
+		}
+		defs = append(defs, def)
+	}
+	return defs, nil
+}
+
+// AddRuleDefs parses each of defs and adds it to the rule set, see CMPRule.ParseRuleDef;
+// it stops and returns an error at first rule fails to parse, rules before it are kept in the set
+func (rs *RuleSet) AddRuleDefs(defs ...RuleDef) error {
+	for _, def := range defs {
+		rule := rs.newRuleFunc()
+		if err := rule.ParseRuleDef(def); err != nil {
+			return fmt.Errorf("failed to parse rule %v, %w", def, err)
+		}
+		rs.add(&Expr{rawExpr: rule.String(), root: &exprRuleNode{rule: rule}}, Source{})
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+// cmprule_test
+package cmprule
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseRuleDef(t *testing.T) {
+	input := testStruct{Num1: -120, Num_uint1: 120, Float1: 12.5, Str1: `say "hi" \o/`}
+	cases := []struct {
+		def    RuleDef
+		text   string
+		result bool
+		err    bool
+	}{
+		{RuleDef{"Num1", "==", -120}, "Num1 : == : -120", true, false},
+		{RuleDef{"Num1", ">", json.Number("100")}, "Num1 : > : 100", false, false},
+		{RuleDef{"Float1", "<", 12.75}, "Float1 : < : 12.75", true, false},
+		{RuleDef{"Num_uint1", "in", map[string]interface{}{"min": 100, "max": "200"}}, "Num_uint1 : in : 100 200", true, false},
+		{RuleDef{"Num1", "notin", []interface{}{-200, 0}}, "Num1 : notin : -200 0", false, false},
+		{RuleDef{"Num1", "is", []interface{}{1, -120}}, "Num1 : is : 1 -120", true, false},
+		{RuleDef{"Str1", "same", []string{"a", `say "hi" \o/`}}, `Str1 : same : "a" "say \"hi\" \o/"`, true, false},
+		{RuleDef{"Str1", "differ", []string{`a\"`, "b"}}, `Str1 : differ : "a\\"" "b"`, true, false},
+		{RuleDef{"Str1", "suffix", []string{`\\/`, `\o/`}}, `Str1 : suffix : "\\/" "\o/"`, true, false},
+		{RuleDef{"Str1", "contain", `\o/`}, `Str1 : contain : "\o/"`, true, false},
+		{RuleDef{"Str1", "match", `\\o/$`}, `Str1 : match : "\\o/$"`, true, false},
+		{RuleDef{"Str1", "notmatch", `\d`}, `Str1 : notmatch : "\d"`, true, false},
+		{RuleDef{"Str1", "match", []interface{}{`^say ".*"`}}, `Str1 : match : "^say \".*\""`, true, false},
+		{RuleDef{"Bool1", "not", []interface{}{true}}, "Bool1 : not : true", true, false},
+		//invalid definitions
+		{RuleDef{"Num1", "==", nil}, "", false, true},
+		{RuleDef{"Num1", "==", []interface{}{1, 2}}, "", false, true},
+		{RuleDef{"Num1", "in", []interface{}{1}}, "", false, true},
+		{RuleDef{"Num1", "in", map[string]interface{}{"min": 1, "high": 2}}, "", false, true},
+		{RuleDef{"Num1", "is", []interface{}{}}, "", false, true},
+		{RuleDef{"Num1", "~", 1}, "", false, true},
+		{RuleDef{"Num1", "==", []interface{}{[]interface{}{1}}}, "", false, true},
+		{RuleDef{"Str1", "match", "(a"}, "", false, true},
+		{RuleDef{"Str1", "differ", []string{`a\`, "b"}}, "", false, true},
+		{RuleDef{"Str1", "suffix", `\`}, "", false, true},
+		{RuleDef{"Num1[", "==", 1}, "", false, true},
+	}
+	for _, c := range cases {
+		cmp := NewDefaultCMPRule()
+		err := cmp.ParseRuleDef(c.def)
+		t.Logf("def %+v: %v, err: %v", c.def, cmp, err)
+		if (err != nil) != c.err {
+			t.Fatalf("def %+v unexpected err: %v", c.def, err)
+		}
+		if err != nil {
+			continue
+		}
+		if cmp.String() != c.text {
+			t.Fatalf("def %+v expect text %v, got %v", c.def, c.text, cmp.String())
+		}
+		result, err := cmp.Compare(input)
+		if err != nil || result != c.result {
+			t.Fatalf("def %+v expect %v, got %v, %v", c.def, c.result, result, err)
+		}
+		//round-trip via text form and RuleDef
+		text := NewDefaultCMPRule()
+		if err := text.ParseRule(cmp.String()); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(text.RuleDef(), cmp.RuleDef()) {
+			t.Fatalf("def %+v round-trip, expect %+v, got %+v", c.def, cmp.RuleDef(), text.RuleDef())
+		}
+		def := NewDefaultCMPRule()
+		if err := def.ParseRuleDef(cmp.RuleDef()); err != nil || def.String() != c.text {
+			t.Fatalf("def %+v round-trip, expect %v, got %v, %v", c.def, c.text, def.String(), err)
+		}
+	}
+}
+
+func TestParseRuleDefsJSON(t *testing.T) {
+	defs, err := ParseRuleDefsJSON([]byte(`[
+		{"field": "Num1", "op": ">", "value": 100},
+		{"field": "Num_uint1", "op": "in", "value": {"min": 100, "max": 18446744073709551615}},
+		{"field": "Str1", "op": "same", "value": ["a", "b \"c\""]}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"Num1 : > : 100",
+		"Num_uint1 : in : 100 18446744073709551615",
+		`Str1 : same : "a" "b \"c\""`,
+	}
+	rs := NewRuleSet()
+	if err := rs.AddRuleDefs(defs...); err != nil {
+		t.Fatal(err)
+	}
+	results := rs.Evaluate(testStruct{Num1: 200, Num_uint1: 120, Str1: `b "c"`})
+	for i, r := range results {
+		if r.Rule != expected[i] || !r.Passed() {
+			t.Fatalf("expect %v passed, got %+v", expected[i], r)
+		}
+	}
+	data, err := json.Marshal(RuleDef{"Num1", "in", map[string]interface{}{"min": 1, "max": 2}})
+	if err != nil || string(data) != `{"field":"Num1","op":"in","value":{"max":2,"min":1}}` {
+		t.Fatalf("unexpected JSON %s, %v", data, err)
+	}
+	for _, bad := range []string{`{"field": "Num1"}`, `[{"field": "Num1", "operator": ">"}]`, `[`} {
+		if _, err := ParseRuleDefsJSON([]byte(bad)); err == nil {
+			t.Fatalf("expect error for %v", bad)
+		}
+	}
+	if err := rs.AddRuleDefs(RuleDef{"Num1", "in", 1}); err == nil {
+		t.Fatal("expect error for invalid definition")
+	}
+}
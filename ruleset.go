@@ -0,0 +1,102 @@
+// Copyright 2020 Hu Jun. All rights reserved.
+// This project is licensed under the terms of the MIT license.
+// license that can be found in the LICENSE file.
+
+package cmprule
+
+// RuleResult is the result of evaluating a single rule of a RuleSet
+type RuleResult struct {
+	// Rule is the raw rule text
+	Rule string
+	// Result is true if the rule passed
+	Result bool
+	// Err is non-nil if the comparison could not be done
+	Err error
+	// Detail is the detailed result, see CompareResult
+	Detail *CompareResult
+	// Source is the location of the rule in a rule file, zero if the rule is not loaded from a file
+	Source Source
+}
+
+// Passed returns true if the rule passed without error
+func (r RuleResult) Passed() bool {
+	return r.Err == nil && r.Result
+}
+
+// RuleSet represents a list of rules, which are evaluated against the same struct;
+// each rule could be a single rule or a boolean expression of rules, see ParseExpr
+type RuleSet struct {
+	rules       []*Expr
+	sources     []Source
+	newRuleFunc func() *CMPRule
+}
+
+// NewRuleSet returns an empty RuleSet, rules added to it are parsed by CMPRule instances created by NewDefaultCMPRule
+func NewRuleSet() *RuleSet {
+	return &RuleSet{
+		newRuleFunc: NewDefaultCMPRule,
+	}
+}
+
+// SetNewRuleFunc set f as function to create the CMPRule instance for each rule added by ParseRules afterwards,
+// this could be used to customize the rule format, see CMPRule.SetxxxFunc().
+// default function is NewDefaultCMPRule
+func (rs *RuleSet) SetNewRuleFunc(f func() *CMPRule) {
+	rs.newRuleFunc = f
+}
+
+// ParseRules parses each of rawrules and adds it to the rule set,
+// it stops and returns an error at first rule fails to parse, rules before it are kept in the set
+func (rs *RuleSet) ParseRules(rawrules ...string) error {
+	for _, rawrule := range rawrules {
+		rule, err := ParseExprWithFunc(rawrule, rs.newRuleFunc)
+		if err != nil {
+			//err already names the rule
+			return err
+		}
+		rs.add(rule, Source{})
+	}
+	return nil
+}
+
+// add appends rule loaded from src to the set
+func (rs *RuleSet) add(rule *Expr, src Source) {
+	rs.rules = append(rs.rules, rule)
+	rs.sources = append(rs.sources, src)
+}
+
+// Len returns number of rules in the set
+func (rs *RuleSet) Len() int {
+	return len(rs.rules)
+}
+
+// Evaluate compares input against every rule in the set, in the order they are added;
+// it returns a RuleResult for each rule
+func (rs *RuleSet) Evaluate(input interface{}) []RuleResult {
+	results := make([]RuleResult, len(rs.rules))
+	for i, rule := range rs.rules {
+		results[i] = newRuleResult(rule.String(), rule.CompareDetailed(input), rs.sources[i])
+	}
+	return results
+}
+
+// newRuleResult returns the RuleResult of rule with detailed result detail
+func newRuleResult(rule string, detail *CompareResult, src Source) RuleResult {
+	return RuleResult{
+		Rule:   rule,
+		Result: detail.Result,
+		Err:    detail.Err,
+		Detail: detail,
+		Source: src,
+	}
+}
+
+// AllPassed returns true if every result in results passed without error
+func AllPassed(results []RuleResult) bool {
+	for _, r := range results {
+		if !r.Passed() {
+			return false
+		}
+	}
+	return true
+}
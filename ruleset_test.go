@@ -0,0 +1,71 @@
+// cmprule_test
+package cmprule
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRuleSet(t *testing.T) {
+	rs := NewRuleSet()
+	err := rs.ParseRules(
+		"Num1:==:-120",
+		"Num1:>:100",
+		`Str1:contain:"test"`,
+		"Num_notexist:==:100",
+		"Lv2Num1:in:100 300",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rs.Len() != 5 {
+		t.Fatalf("expect 5 rules, got %d", rs.Len())
+	}
+	input := testStruct{Num1: -120, Str1: "test1"}
+	expected := []testResult{
+		{"Num1:==:-120", true, false},
+		{"Num1:>:100", false, false},
+		{`Str1:contain:"test"`, true, false},
+		{"Num_notexist:==:100", false, true},
+		{"Lv2Num1:in:100 300", false, true},
+	}
+	results := rs.Evaluate(input)
+	if len(results) != len(expected) {
+		t.Fatalf("expect %d results, got %d", len(expected), len(results))
+	}
+	for i, r := range results {
+		t.Logf("rule: %v; result: %v, err: %v", r.Rule, r.Result, r.Err)
+		if r.Rule != expected[i].in {
+			t.Fatalf("expect rule %v, got %v", expected[i].in, r.Rule)
+		}
+		if (r.Err != nil) != expected[i].expect_err {
+			t.Fatalf("rule %v unexpected err: %v", r.Rule, r.Err)
+		}
+		if r.Result != expected[i].out_bool {
+			t.Fatalf("rule %v expect %v, got %v", r.Rule, expected[i].out_bool, r.Result)
+		}
+	}
+	if AllPassed(results) {
+		t.Fatal("expect not all rules passed")
+	}
+	if !AllPassed(results[:1]) {
+		t.Fatal("expect all rules passed")
+	}
+	//parse error
+	err = rs.ParseRules("Num1:in:100", "Num1:==:1")
+	if err == nil {
+		t.Fatal("expect parse error")
+	}
+	t.Logf("expected err: %v", err)
+	if strings.Count(err.Error(), "failed to parse rule") != 1 || strings.Contains(err.Error(), "at column") {
+		t.Fatalf("unexpected err message: %v", err)
+	}
+	//a rule in an expression is located by its column
+	err = rs.ParseRules("Num1:>:1 and Num1:in:100")
+	if err == nil || !strings.Contains(err.Error(), "failed to parse rule Num1:in:100 at column 13") {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if rs.Len() != 5 {
+		t.Fatalf("expect 5 rules, got %d", rs.Len())
+	}
+}
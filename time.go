@@ -0,0 +1,233 @@
+// Copyright 2020 Hu Jun. All rights reserved.
+// This project is licensed under the terms of the MIT license.
+// license that can be found in the LICENSE file.
+
+package cmprule
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// anchors of relative time values
+const (
+	timeNow       = "now"
+	timeToday     = "today"
+	timeYesterday = "yesterday"
+	timeTomorrow  = "tomorrow"
+)
+
+// defaultTimeLayouts are the layouts tried in order by default parse time function;
+// fractional seconds are accepted after the seconds of any layout
+var defaultTimeLayouts = []string{
+	TimeFMTStr,
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// timeValue is a parsed time value of a rule,
+// which is either an absolute time, or a time relative to the time of comparison like "now-5m"
+type timeValue struct {
+	abs time.Time
+	// anchor is empty for an absolute time
+	anchor string
+	offset time.Duration
+}
+
+// resolve returns the time of v, now is the time of comparison in the time location of the rule
+func (v timeValue) resolve(now time.Time) time.Time {
+	switch v.anchor {
+	case "":
+		return v.abs
+	case timeNow:
+		return now.Add(v.offset)
+	}
+	y, m, d := now.Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+	switch v.anchor {
+	case timeYesterday:
+		day = day.AddDate(0, 0, -1)
+	case timeTomorrow:
+		day = day.AddDate(0, 0, 1)
+	}
+	return day.Add(v.offset)
+}
+
+// timeValues is rule values parsed for time.Time
+type timeValues struct {
+	single timeValue
+	min    timeValue
+	max    timeValue
+	list   []timeValue
+}
+
+// parseTimeInLayouts parses timestr with each of the time layouts of the rule until one succeeds,
+// a time without zone is in the time location of the rule
+func (cmprule *CMPRule) parseTimeInLayouts(timestr string) (time.Time, error) {
+	var err error
+	for _, layout := range cmprule.timeLayouts {
+		var t time.Time
+		t, err = time.ParseInLocation(layout, timestr, cmprule.timeLocation)
+		if err == nil {
+			return t, nil
+		}
+	}
+	if err == nil {
+		err = fmt.Errorf("no time layout")
+	}
+	return time.Time{}, err
+}
+
+// parseTimeValue parses timestr as a relative time like "now", "today+8h" or "now-5m",
+// where the offset is parsed as time.Duration; otherwise it is parsed as an absolute time
+func (cmprule *CMPRule) parseTimeValue(timestr string) (timeValue, error) {
+	lower := strings.ToLower(timestr)
+	for _, anchor := range []string{timeNow, timeToday, timeYesterday, timeTomorrow} {
+		if !strings.HasPrefix(lower, anchor) {
+			continue
+		}
+		v := timeValue{anchor: anchor}
+		rest := timestr[len(anchor):]
+		if rest == "" {
+			return v, nil
+		}
+		if rest[0] != '+' && rest[0] != '-' {
+			break
+		}
+		d, err := cmprule.parseDurationInt64Func(rest[1:])
+		if err != nil {
+			return v, err
+		}
+		v.offset = time.Duration(d)
+		if rest[0] == '-' {
+			v.offset = -v.offset
+		}
+		return v, nil
+	}
+	t, err := cmprule.parseTimeFunc(timestr)
+	return timeValue{abs: t}, err
+}
+
+// prepareTime returns rule values parsed for time.Time, the parsed values are cached,
+// relative values are resolved at compare time
+func (cmprule *CMPRule) prepareTime() (*timeValues, error) {
+	cmprule.preparedLock.Lock()
+	defer cmprule.preparedLock.Unlock()
+	if cmprule.preparedTime != nil {
+		return cmprule.preparedTime, nil
+	}
+	values := new(timeValues)
+	var err error
+	switch detectType(cmprule.ruleOp) {
+	case valueSingle:
+		values.single, err = cmprule.parseTimeValue(cmprule.ruleVal)
+		if err != nil {
+			return nil, cmprule.valueError(cmprule.ruleVal, fmt.Sprintf("can't parse %v into time", cmprule.ruleVal), err)
+		}
+	case valueRange:
+		values.min, err = cmprule.parseTimeValue(cmprule.numMinStr)
+		if err != nil {
+			return nil, cmprule.valueError(cmprule.numMinStr, "invalid range value", err)
+		}
+		values.max, err = cmprule.parseTimeValue(cmprule.numMaxStr)
+		if err != nil {
+			return nil, cmprule.valueError(cmprule.numMaxStr, "invalid range value", err)
+		}
+		now := cmprule.now()
+		if values.max.resolve(now).Before(values.min.resolve(now)) {
+			return nil, cmprule.valueError(cmprule.ruleVal, "invalid range value, max value is smaller than min value", nil)
+		}
+	case valueList:
+		values.list = []timeValue{}
+		for _, str := range cmprule.numListStr {
+			v, err := cmprule.parseTimeValue(str)
+			if err != nil {
+				return nil, cmprule.valueError(str, "invalid list value", err)
+			}
+			values.list = append(values.list, v)
+		}
+	default:
+		return nil, cmprule.opError("time.Time")
+	}
+	cmprule.preparedTime = values
+	return values, nil
+}
+
+// now returns the current time of the clock in the time location of the rule
+func (cmprule *CMPRule) now() time.Time {
+	return cmprule.clock().In(cmprule.timeLocation)
+}
+
+// compareTime compares input against values in nanosecond precision, times in different zones are compared by the instant
+func (cmprule *CMPRule) compareTime(input time.Time, values *timeValues) (bool, error) {
+	now := cmprule.now()
+	switch detectType(cmprule.ruleOp) {
+	case valueSingle:
+		v := values.single.resolve(now)
+		switch cmprule.ruleOp {
+		case opNumEq:
+			return input.Equal(v), nil
+		case opNumNotEq:
+			return !input.Equal(v), nil
+		case opNumLE:
+			return !input.Before(v), nil
+		case opNumSE:
+			return !input.After(v), nil
+		case opNumL:
+			return input.After(v), nil
+		case opNumS:
+			return input.Before(v), nil
+		}
+	case valueRange:
+		in := !input.Before(values.min.resolve(now)) && !input.After(values.max.resolve(now))
+		switch cmprule.ruleOp {
+		case opNumIN:
+			return in, nil
+		case opNumNotIN:
+			return !in, nil
+		}
+	case valueList:
+		found := false
+		for _, v := range values.list {
+			if input.Equal(v.resolve(now)) {
+				found = true
+				break
+			}
+		}
+		switch cmprule.ruleOp {
+		case opNumIs:
+			return found, nil
+		case opNumNot:
+			return !found, nil
+		}
+	}
+	return false, cmprule.opError("time.Time")
+}
+
+// SetParseTimeFunc set f as function to parse a string that represents an absolute time into time.Time.
+// this is used only by type time.Time, relative values like "now-5m" are parsed before f is called.
+// default function tries the layouts set by SetTimeLayouts in order, in the location set by SetTimeLocation
+func (cmprule *CMPRule) SetParseTimeFunc(f func(timestr string) (time.Time, error)) {
+	cmprule.parseTimeFunc = f
+}
+
+// SetTimeLayouts set the layouts tried in order by default parse time function,
+// default layouts are TimeFMTStr, time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05" and "2006-01-02"
+func (cmprule *CMPRule) SetTimeLayouts(layouts ...string) {
+	cmprule.timeLayouts = layouts
+}
+
+// SetTimeLocation set loc as the location of a time value without zone, and the location of relative time values like "today";
+// default is time.UTC
+func (cmprule *CMPRule) SetTimeLocation(loc *time.Location) {
+	cmprule.timeLocation = loc
+}
+
+// SetClock set f as function returns the current time, which is used to resolve relative time values like "now-5m";
+// default is time.Now
+func (cmprule *CMPRule) SetClock(f func() time.Time) {
+	cmprule.clock = f
+}
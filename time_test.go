@@ -0,0 +1,109 @@
+// cmprule_test
+package cmprule
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+type testStructTime struct {
+	Stamp  time.Time
+	Local  time.Time
+	Events []time.Time
+}
+
+var test_list_time = []testResult{
+	{"Stamp : > : 2020/03/31T15:00:00", true, false},
+	{"Stamp : == : 2020/03/31T15:00:00.25", true, false},
+	{"Stamp : < : 2020/03/31T15:00:00.251", true, false},
+	{"Stamp : == : 2020-03-31T23:00:00.25+08:00", true, false},
+	{"Stamp : == : 2020-03-31T15:00:00.25Z", true, false},
+	{"Stamp : >= : 2020-03-31 15:00:00.25", true, false},
+	{"Stamp : > : 2020-03-31", true, false},
+	{"Local : == : 2020/03/31T15:00:00", true, false},
+	{"Local : in : 2020-03-31T22:59:59+08:00 2020-03-31T23:00:01+08:00", true, false},
+	{"Local : notin : 2020-03-31T22:59:59+08:00 2020-03-31T23:00:01+08:00", false, false},
+	{"Events[*] : in : 2020/03/31T15:00:00.249 2020/03/31T15:00:00.251", true, false},
+	{"Events[*] : is : 2020/03/31T15:00:00.25 2020/03/31T15:00:00.249", false, false},
+	{"any(Events) : == : 2020/03/31T15:00:00.251", true, false},
+	{"Stamp : >= : now-5m", true, false},
+	{"Stamp : >= : NOW-1m", false, false},
+	{"Stamp : in : now-3m now", true, false},
+	{"Stamp : > : today+14h", true, false},
+	{"Stamp : < : tomorrow", true, false},
+	{"Stamp : > : yesterday+23h59m", true, false},
+	{"Stamp : < : now+", false, true},
+	{"Stamp : < : nowhere", false, true},
+	{"Stamp : in : now now-1m", false, true},
+	{"Stamp : == : 31/03/2020", false, true},
+	{"Stamp : contain : \"2020\"", false, true},
+}
+
+func TestTimeCompare(t *testing.T) {
+	base := time.Date(2020, 3, 31, 15, 0, 0, 250*int(time.Millisecond), time.UTC)
+	cst := time.FixedZone("CST", 8*3600)
+	input := testStructTime{
+		Stamp:  base,
+		Local:  time.Date(2020, 3, 31, 23, 0, 0, 0, cst),
+		Events: []time.Time{base.Add(-time.Millisecond), base, base.Add(time.Millisecond)},
+	}
+	newRule := func() *CMPRule {
+		cmp := NewDefaultCMPRule()
+		cmp.SetClock(func() time.Time {
+			return base.Add(2 * time.Minute)
+		})
+		return cmp
+	}
+	tableTestWithFunc(input, test_list_time, newRule, t)
+}
+
+func TestTimeOptions(t *testing.T) {
+	cst := time.FixedZone("CST", 8*3600)
+	input := testStructTime{Stamp: time.Date(2020, 3, 31, 15, 0, 0, 0, time.UTC)}
+	check := func(cmp *CMPRule, rule string, expect bool) {
+		t.Helper()
+		if err := cmp.ParseRule(rule); err != nil {
+			t.Fatal(err)
+		}
+		if r, err := cmp.Compare(input); err != nil || r != expect {
+			t.Fatalf("rule %v: expect %v, got %v, %v", rule, expect, r, err)
+		}
+	}
+	//a value without zone is in the time location
+	cmp := NewDefaultCMPRule()
+	cmp.SetTimeLocation(cst)
+	check(cmp, "Stamp : == : 2020-03-31 23:00:00", true)
+	check(cmp, "Stamp : == : 2020-03-31T15:00:00Z", true)
+	//today is the midnight in the time location
+	cmp.SetClock(func() time.Time { return input.Stamp })
+	check(cmp, "Stamp : == : today+23h", true)
+	//custom layouts
+	cmp = NewDefaultCMPRule()
+	cmp.SetTimeLayouts(time.RFC1123)
+	check(cmp, "Stamp : == : Tue, 31 Mar 2020 15:00:00 UTC", true)
+	if err := cmp.ParseRule("Stamp : == : 2020/03/31T15:00:00"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cmp.Compare(input); err == nil {
+		t.Fatal("expect error for a value not in layouts")
+	}
+	//Unix seconds returned by the legacy parse function
+	cmp = NewDefaultCMPRule()
+	cmp.SetparseTimeInt64Func(func(timestr string) (int64, error) {
+		return 1585666800, nil
+	})
+	check(cmp, "Stamp : == : anytime", true)
+}
+
+func TestTimeExplain(t *testing.T) {
+	input := testStructTime{Stamp: time.Date(2020, 3, 31, 15, 0, 0, 5e6, time.UTC)}
+	cmp := NewDefaultCMPRule()
+	if err := cmp.ParseRule("Stamp : < : 2020/03/31T15:00:00"); err != nil {
+		t.Fatal(err)
+	}
+	s := cmp.Explain(input)
+	if !strings.HasPrefix(s, "Stamp = 2020-03-31T15:00:00.005Z, expected < ") {
+		t.Fatalf("unexpected explanation %v", s)
+	}
+}
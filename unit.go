@@ -0,0 +1,238 @@
+// Copyright 2020 Hu Jun. All rights reserved.
+// This project is licensed under the terms of the MIT license.
+// license that can be found in the LICENSE file.
+
+package cmprule
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// tagOptionUnit is the option in cmprule struct tag declares the unit of a field, like `cmprule:"rx_rate,unit=bps"`
+const tagOptionUnit = "unit"
+
+// units maps the accepted unit names to the base unit
+var units = map[string]string{
+	"b":     "b",
+	"bit":   "b",
+	"bits":  "b",
+	"B":     "B",
+	"byte":  "B",
+	"bytes": "B",
+	"bps":   "bps",
+	"b/s":   "bps",
+	"bit/s": "bps",
+	"Bps":   "Bps",
+	"B/s":   "Bps",
+	"pps":   "pps",
+}
+
+// unitPrefixes maps SI and IEC prefixes to their multipliers
+var unitPrefixes = map[string]uint64{
+	"k":  1e3,
+	"K":  1e3,
+	"M":  1e6,
+	"G":  1e9,
+	"T":  1e12,
+	"P":  1e15,
+	"E":  1e18,
+	"Ki": 1 << 10,
+	"Mi": 1 << 20,
+	"Gi": 1 << 30,
+	"Ti": 1 << 40,
+	"Pi": 1 << 50,
+	"Ei": 1 << 60,
+}
+
+// parseUnitNumber splits numstr like "9.5Gbps" or "512 MiB" into the number "9.5", the multiplier of the prefix,
+// and the base unit, which is empty if numstr has no unit
+func parseUnitNumber(numstr string) (string, uint64, string, error) {
+	if _, err := strconv.ParseFloat(numstr, 64); err == nil {
+		return numstr, 1, "", nil
+	}
+	i := 0
+	if i < len(numstr) && (numstr[i] == '+' || numstr[i] == '-') {
+		i++
+	}
+	if strings.HasPrefix(numstr[i:], "0x") || strings.HasPrefix(numstr[i:], "0X") {
+		i += 2
+		for i < len(numstr) && strings.IndexByte("0123456789abcdefABCDEF", numstr[i]) >= 0 {
+			i++
+		}
+	} else {
+		for i < len(numstr) && (numstr[i] >= '0' && numstr[i] <= '9' || numstr[i] == '.') {
+			i++
+		}
+		//exponent, like 1e6
+		if i+1 < len(numstr) && (numstr[i] == 'e' || numstr[i] == 'E') {
+			j := i + 1
+			if numstr[j] == '+' || numstr[j] == '-' {
+				j++
+			}
+			if j < len(numstr) && numstr[j] >= '0' && numstr[j] <= '9' {
+				for j < len(numstr) && numstr[j] >= '0' && numstr[j] <= '9' {
+					j++
+				}
+				i = j
+			}
+		}
+	}
+	num, suffix := numstr[:i], strings.TrimSpace(numstr[i:])
+	if suffix == "" {
+		return num, 1, "", nil
+	}
+	if base, ok := units[suffix]; ok {
+		return num, 1, base, nil
+	}
+	for _, n := range []int{2, 1} {
+		if len(suffix) < n {
+			continue
+		}
+		mul, ok := unitPrefixes[suffix[:n]]
+		if !ok {
+			continue
+		}
+		if suffix[n:] == "" {
+			return num, mul, "", nil
+		}
+		if base, ok := units[suffix[n:]]; ok {
+			return num, mul, base, nil
+		}
+	}
+	return "", 0, "", fmt.Errorf("unknown unit %v in %v", suffix, numstr)
+}
+
+// parseUnitRat parses numstr with unit into an exact rational number in base unit
+func parseUnitRat(numstr string) (*big.Rat, error) {
+	num, mul, _, err := parseUnitNumber(numstr)
+	if err != nil {
+		return nil, err
+	}
+	r, ok := new(big.Rat).SetString(num)
+	if !ok {
+		return nil, fmt.Errorf("invalid number %v", numstr)
+	}
+	return r.Mul(r, new(big.Rat).SetInt(new(big.Int).SetUint64(mul))), nil
+}
+
+// ParseUnitInt64 parses a number with an optional SI or IEC prefix and unit into int64 in base unit,
+// like "9.5Gbps" into 9500000000 and "512MiB" into 536870912;
+// the result must be an integer.
+// it could be used with CMPRule.SetParseNumInt64Func
+func ParseUnitInt64(numstr string) (int64, error) {
+	r, err := parseUnitRat(numstr)
+	if err != nil {
+		return 0, err
+	}
+	if !r.IsInt() || !r.Num().IsInt64() {
+		return 0, fmt.Errorf("%v is not an int64 value", numstr)
+	}
+	return r.Num().Int64(), nil
+}
+
+// ParseUnitUint64 is same as ParseUnitInt64, except the result is uint64,
+// it could be used with CMPRule.SetParseUint64Func
+func ParseUnitUint64(numstr string) (uint64, error) {
+	r, err := parseUnitRat(numstr)
+	if err != nil {
+		return 0, err
+	}
+	if !r.IsInt() || !r.Num().IsUint64() {
+		return 0, fmt.Errorf("%v is not an uint64 value", numstr)
+	}
+	return r.Num().Uint64(), nil
+}
+
+// ParseUnitFloat64 is same as ParseUnitInt64, except the result is float64, NaN and Inf are also accepted,
+// it could be used with CMPRule.SetParseFloat64Func
+func ParseUnitFloat64(numstr string) (float64, error) {
+	num, mul, _, err := parseUnitNumber(numstr)
+	if err != nil {
+		return 0, err
+	}
+	if mul == 1 {
+		return strconv.ParseFloat(num, 64)
+	}
+	r, ok := new(big.Rat).SetString(num)
+	if !ok {
+		return 0, fmt.Errorf("invalid number %v", numstr)
+	}
+	f, _ := r.Mul(r, new(big.Rat).SetInt(new(big.Int).SetUint64(mul))).Float64()
+	if math.IsInf(f, 0) {
+		return 0, fmt.Errorf("%v is out of float64 range", numstr)
+	}
+	return f, nil
+}
+
+// resolveUnit returns the base unit declared in the struct tag of the field specified by path in type t,
+// the unit applies to all elements of a slice, array or map field;
+// it returns an empty string if no unit is declared, or the field is not a struct field
+func (fr fieldResolver) resolveUnit(t reflect.Type, path []pathSegment) (string, error) {
+	if len(path) == 0 || path[len(path)-1].name == "" {
+		return "", nil
+	}
+	parent, err := fr.resolveType(t, path[:len(path)-1])
+	if err != nil {
+		return "", err
+	}
+	if parent.Kind() != reflect.Struct {
+		return "", nil
+	}
+	f, err := fr.lookupField(parent, path[len(path)-1].name)
+	if err != nil {
+		return "", err
+	}
+	unit := tagOption(f.Tag.Get(tagName), tagOptionUnit)
+	if base, ok := units[unit]; ok {
+		return base, nil
+	}
+	return unit, nil
+}
+
+// fieldUnit returns the unit declared by the field of the rule in root type t, it is cached for each type
+func (cmprule *CMPRule) fieldUnit(t reflect.Type) (string, error) {
+	cmprule.preparedLock.Lock()
+	defer cmprule.preparedLock.Unlock()
+	if unit, ok := cmprule.fieldUnits[t]; ok {
+		return unit, nil
+	}
+	unit, err := cmprule.resolver.resolveUnit(t, cmprule.fieldPath)
+	if err != nil {
+		return "", err
+	}
+	if cmprule.fieldUnits == nil {
+		cmprule.fieldUnits = make(map[reflect.Type]string)
+	}
+	cmprule.fieldUnits[t] = unit
+	return unit, nil
+}
+
+// checkUnit returns an error if a value of the rule has a unit other than the unit declared by the field in root type t;
+// a value without unit is in the unit of the field, nothing is checked if the field declares no unit
+func (cmprule *CMPRule) checkUnit(t reflect.Type) error {
+	if cmprule.fieldExpr != nil || cmprule.ref != nil {
+		return nil
+	}
+	unit, err := cmprule.fieldUnit(t)
+	if err != nil || unit == "" {
+		//an unresolvable field is reported when it is compared
+		return nil
+	}
+	values := cmprule.valueStrings()
+	if isApproxOp(cmprule.ruleOp) {
+		values = []string{cmprule.approxValStr}
+	}
+	for _, s := range values {
+		_, _, vunit, err := parseUnitNumber(s)
+		if err != nil || vunit == "" || vunit == unit {
+			continue
+		}
+		return cmprule.valueError(s, fmt.Sprintf("unit %v of value %v doesn't match unit %v of field %v", vunit, s, unit, cmprule.ruleFieldName), nil)
+	}
+	return nil
+}
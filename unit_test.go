@@ -0,0 +1,107 @@
+// cmprule_test
+package cmprule
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type testStructUnit struct {
+	Throughput float64  `cmprule:"throughput,unit=bps"`
+	Mem        uint64   `cmprule:",unit=bytes"`
+	Pkts       int64    `cmprule:"pkts,unit=pps"`
+	Rates      []uint64 `cmprule:",unit=bps"`
+	Count      int
+}
+
+func TestParseUnit(t *testing.T) {
+	//an error is expected if the expected value is nil
+	cases := []struct {
+		in  string
+		i64 interface{}
+		u64 interface{}
+		f64 interface{}
+	}{
+		{"100", int64(100), uint64(100), float64(100)},
+		{"1.5k", int64(1500), uint64(1500), float64(1500)},
+		{"9.5Gbps", int64(9500000000), uint64(9500000000), 9.5e9},
+		{"512MiB", int64(536870912), uint64(536870912), float64(536870912)},
+		{"512 MiB", int64(536870912), uint64(536870912), float64(536870912)},
+		{"1Ki", int64(1024), uint64(1024), float64(1024)},
+		{"10Mpps", int64(10000000), uint64(10000000), 1e7},
+		{"8bits", int64(8), uint64(8), float64(8)},
+		{"1e3kB", int64(1000000), uint64(1000000), 1e6},
+		{"0x10k", int64(16000), uint64(16000), float64(16000)},
+		{"-2k", int64(-2000), nil, float64(-2000)},
+		{"8Ei", nil, uint64(1 << 63), float64(1 << 63)},
+		{"1.5B", nil, nil, 1.5},
+		{"10Qbps", nil, nil, nil},
+		{"abc", nil, nil, nil},
+	}
+	for _, c := range cases {
+		for _, r := range []struct {
+			expect interface{}
+			parse  func(string) (interface{}, error)
+		}{
+			{c.i64, func(s string) (interface{}, error) { return ParseUnitInt64(s) }},
+			{c.u64, func(s string) (interface{}, error) { return ParseUnitUint64(s) }},
+			{c.f64, func(s string) (interface{}, error) { return ParseUnitFloat64(s) }},
+		} {
+			v, err := r.parse(c.in)
+			if (r.expect == nil) != (err != nil) || (err == nil && v != r.expect) {
+				t.Fatalf("parse %v: expect %v, got %v, %v", c.in, r.expect, v, err)
+			}
+		}
+	}
+}
+
+func newUnitCMPRule() *CMPRule {
+	cmp := NewDefaultCMPRule()
+	cmp.SetParseNumInt64Func(ParseUnitInt64)
+	cmp.SetParseUint64Func(ParseUnitUint64)
+	cmp.SetParseFloat64Func(ParseUnitFloat64)
+	return cmp
+}
+
+var test_list_unit = []testResult{
+	{"throughput : >= : 9.5Gbps", true, false},
+	{"throughput : in : 9G 10Gbps", true, false},
+	{"throughput : ~= : 10Gbps 5%", true, false},
+	{"throughput : ~= : 9.7Gbps ±200Mbps", true, false},
+	{"throughput : !~= : 9.7G 50M", true, false},
+	{"Count : approx : 1.9k ±0.1k", true, false},
+	{"Count : approx : 1.5k ±10%", false, false},
+	{"throughput : >= : 1.2GBps", false, true},
+	{"Mem : < : 512MiB", true, false},
+	{"Mem : is : 400MiB 512MiB", true, false},
+	{"Mem : < : 512Mbps", false, true},
+	{"pkts : > : 1.5Mpps", false, false},
+	{"pkts : >= : 1.5M", true, false},
+	{"pkts : > : 1.5MB", false, true},
+	{"Rates[*] : >= : 1Gbps", true, false},
+	{"Rates[1] : > : 1GB", false, true},
+	{"Count : == : 2k", true, false},
+	{"Count : == : 2kB", true, false},
+}
+
+func TestUnitCompare(t *testing.T) {
+	input := &testStructUnit{Throughput: 9.8e9, Mem: 400 << 20, Pkts: 1500000, Rates: []uint64{1e9, 10e9}, Count: 2000}
+	tableTestWithFunc(input, test_list_unit, newUnitCMPRule, t)
+	//a unit mismatch is a ParseError with column, and Validate reports the same error
+	for _, tt := range test_list_unit {
+		cmp := newUnitCMPRule()
+		if err := cmp.ParseRule(tt.in); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := cmp.Compare(input); err != nil {
+			var pe *ParseError
+			if !errors.As(err, &pe) || pe.Column < 0 {
+				t.Fatalf("input: %v, expect a ParseError with column, got %#v", tt.in, err)
+			}
+		}
+		if err := cmp.Validate(reflect.TypeOf(input)); (err != nil) != tt.expect_err {
+			t.Fatalf("input: %v, unexpected validate err: %v", tt.in, err)
+		}
+	}
+}
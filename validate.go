@@ -0,0 +1,236 @@
+// Copyright 2020 Hu Jun. All rights reserved.
+// This project is licensed under the terms of the MIT license.
+// license that can be found in the LICENSE file.
+
+package cmprule
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// derefType returns the type t points to, for a pointer type
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// resolveType returns the type of the field specified by path in type t, pointer types are dereferenced;
+// this is the static counterpart of walkField, it returns an interface type if the type of the field
+// could only be known at compare time, like in a decoded JSON document
+func (fr fieldResolver) resolveType(t reflect.Type, path []pathSegment) (reflect.Type, error) {
+	t = derefType(t)
+	if len(path) == 0 || t.Kind() == reflect.Interface {
+		return t, nil
+	}
+	seg := path[0]
+	if seg.name != "" {
+		switch t.Kind() {
+		case reflect.Map:
+			if _, err := parseMapKey(t.Key(), seg.name); err != nil {
+				return nil, invalidKeyError(seg.name, t, err)
+			}
+			t = t.Elem()
+		default:
+			f, err := fr.lookupField(t, seg.name)
+			if err != nil {
+				return nil, err
+			}
+			t = f.Type
+		}
+	}
+	for _, sel := range seg.selectors {
+		t = derefType(t)
+		switch t.Kind() {
+		case reflect.Interface:
+			return t, nil
+		case reflect.Slice, reflect.Array:
+			if !sel.wildcard {
+				index, err := strconv.Atoi(sel.key)
+				if err != nil {
+					return nil, &ParseError{Token: sel.key, Column: -1, Msg: fmt.Sprintf("invalid index %v in %v", sel.key, seg.raw)}
+				}
+				if t.Kind() == reflect.Array && (index < 0 || index >= t.Len()) {
+					return nil, &FieldNotFoundError{Token: sel.key, Column: -1, Type: t.String(),
+						Msg: fmt.Sprintf("index %v out of range in %v, length is %d", index, seg.raw, t.Len())}
+				}
+				if index < 0 {
+					return nil, &FieldNotFoundError{Token: sel.key, Column: -1, Type: t.String(),
+						Msg: fmt.Sprintf("index %v out of range in %v", index, seg.raw)}
+				}
+			}
+		case reflect.Map:
+			if !sel.wildcard {
+				if _, err := parseMapKey(t.Key(), sel.key); err != nil {
+					return nil, invalidKeyError(sel.key, t, err)
+				}
+			}
+		default:
+			return nil, notContainerError(seg.raw, t)
+		}
+		t = t.Elem()
+	}
+	return fr.resolveType(t, path[1:])
+}
+
+// valueStrings returns the value strings of a numberic or bool rule, according to the type of operator
+func (cmprule *CMPRule) valueStrings() []string {
+	switch detectType(cmprule.ruleOp) {
+	case valueRange:
+		return []string{cmprule.numMinStr, cmprule.numMaxStr}
+	case valueList:
+		return cmprule.numListStr
+	default:
+		return []string{cmprule.ruleVal}
+	}
+}
+
+// checkType returns an error if the operator is not valid for type t or the values can't be parsed for type t,
+// t must be dereferenced; numberic values are pre-parsed and cached like in compareElement
+func (cmprule *CMPRule) checkType(t reflect.Type) error {
+	if isApproxOp(cmprule.ruleOp) {
+		return cmprule.checkApproxType(t)
+	}
+	switch t.String() {
+	case "int", "int8", "int16", "int32", "int64":
+		_, err := cmprule.prepareInt64(prepareTypeNum, cmprule.parseNumInt64Func)
+		return err
+	case "time.Duration":
+		_, err := cmprule.prepareInt64(prepareTypeDuration, cmprule.parseDurationInt64Func)
+		return err
+	case "time.Time":
+		_, err := cmprule.prepareTime()
+		return err
+	case "uint", "uint8", "uint16", "uint32", "uint64":
+		_, err := cmprule.prepareUint64(prepareTypeNum, cmprule.parseNumUint64Func)
+		return err
+	case "float32", "float64", "json.Number":
+		_, err := cmprule.prepareFloat64(prepareTypeNum, cmprule.parseNumFloat64Func)
+		return err
+	case "string":
+		if !isStrOp(cmprule.ruleOp) {
+			return cmprule.opError("string")
+		}
+		return nil
+	case "bool":
+		switch cmprule.ruleOp {
+		case opNumEq, opNumNotEq, opNumIs, opNumNot:
+		default:
+			return cmprule.opError("bool")
+		}
+		for _, s := range cmprule.valueStrings() {
+			if _, err := cmprule.parseBoolFunc(s); err != nil {
+				return cmprule.valueError(s, fmt.Sprintf("can't parse %v into bool", s), err)
+			}
+		}
+		return nil
+	case "net.IP", "netip.Addr":
+		_, err := cmprule.checkIPOp(t.String(), false)
+		return err
+	case "net.IPNet", "netip.Prefix":
+		_, err := cmprule.checkIPOp(t.String(), true)
+		return err
+	default:
+		return cmprule.typeError(t)
+	}
+}
+
+// Validate checks the parsed rule against type t, which is a struct or a pointer to struct:
+// the field must exist in t, the operator must be valid for the type of the field, and the values must be parsable;
+// this reports errors which are otherwise only returned by the first Compare.
+// a field of interface type, like a value in a decoded JSON document, can't be checked until compare time,
+// Validate returns nil for such field
+func (cmprule *CMPRule) Validate(t reflect.Type) error {
+	var ft reflect.Type
+	var err error
+	if cmprule.fieldExpr != nil {
+		ft, err = cmprule.fieldExpr.typeOf(cmprule.resolver, t)
+	} else {
+		ft, err = cmprule.resolver.resolveType(t, cmprule.fieldPath)
+	}
+	if err != nil {
+		return cmprule.annotateError(err)
+	}
+	if cmprule.ref != nil {
+		return cmprule.checkRefType(ft, t)
+	}
+	if ft.Kind() == reflect.Interface {
+		return nil
+	}
+	if err := cmprule.checkUnit(t); err != nil {
+		return err
+	}
+	return cmprule.checkType(ft)
+}
+
+// ParseRuleFor is same as ParseRule, except the parsed rule is also validated against type t, see Validate
+func (cmprule *CMPRule) ParseRuleFor(rawrule string, t reflect.Type) error {
+	if err := cmprule.ParseRule(rawrule); err != nil {
+		return err
+	}
+	return cmprule.Validate(t)
+}
+
+// CompileFor is same as Compile, except the compiled rule is also validated against type t, see CMPRule.Validate
+func CompileFor(rawrule string, t reflect.Type) (*Rule, error) {
+	return CompileForWithFunc(rawrule, t, NewDefaultCMPRule)
+}
+
+// CompileForWithFunc is same as CompileWithFunc, except the compiled rule is also validated against type t, see CMPRule.Validate
+func CompileForWithFunc(rawrule string, t reflect.Type, newRule func() *CMPRule) (*Rule, error) {
+	r, err := CompileWithFunc(rawrule, newRule)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.cmprule.Validate(t); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (n *exprAndNode) validate(t reflect.Type) error {
+	if err := n.left.validate(t); err != nil {
+		return err
+	}
+	return n.right.validate(t)
+}
+
+func (n *exprOrNode) validate(t reflect.Type) error {
+	if err := n.left.validate(t); err != nil {
+		return err
+	}
+	return n.right.validate(t)
+}
+
+func (n *exprNotNode) validate(t reflect.Type) error {
+	return n.node.validate(t)
+}
+
+func (n *exprRuleNode) validate(t reflect.Type) error {
+	if err := n.rule.Validate(t); err != nil {
+		return fmt.Errorf("invalid rule %v, %w", n.rule, err)
+	}
+	return nil
+}
+
+// Validate checks every rule in the expression against type t, see CMPRule.Validate
+func (e *Expr) Validate(t reflect.Type) error {
+	return e.root.validate(t)
+}
+
+// Validate checks every rule in the set against type t, see CMPRule.Validate;
+// it returns an error for the first rule fails the check
+func (rs *RuleSet) Validate(t reflect.Type) error {
+	for i, rule := range rs.rules {
+		if err := rule.Validate(t); err != nil {
+			if !rs.sources[i].IsZero() {
+				return fmt.Errorf("%v: rule %v is invalid for %v, %w", rs.sources[i], rule, t, err)
+			}
+			return fmt.Errorf("rule %v is invalid for %v, %w", rule, t, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+// cmprule_test
+package cmprule
+
+import (
+	"reflect"
+	"testing"
+)
+
+type testStructValidate struct {
+	Lv2   testStructLv2
+	Point *testStructLv2
+	Ports []testPort
+	Array [2]int
+	Map   map[int]testPort
+	Doc   map[string]interface{}
+	Any   interface{}
+	Chan  chan int
+	priv  int
+}
+
+func TestValidate(t *testing.T) {
+	validateTest(reflect.TypeOf(testStructValidate{}), []string{
+		"Lv2.Lv2Num1:>:100",
+		"Point.Lv1.Num1:in:-200 -100",
+		"Lv2.Lv1.Num_uint1:is:1 2 0x3",
+		"Lv2.Lv1.Float1:<=:12.5",
+		`Lv2.Lv1.Str1:contain:"test"`,
+		"Lv2.Lv1.Duration1:>:5s",
+		"Lv2.Lv1.Stamp1:>:2020/03/31T15:00:00",
+		"Lv2.Lv1.IP1:within:1.1.1.0/24",
+		"Lv2.Lv1.Bool1:==:true",
+		"Lv2.Lv1.PointBool:not:false",
+		"Ports[*].Errors:==:0",
+		"any(Ports).Queues[1]:==:0",
+		"Array[1]:==:0",
+		"Map[3].State:same:\"up\"",
+		"Map.3.Speed:>:0",
+		"Doc.a.b[*]:==:0",
+		"Any:same:\"x\"",
+	}, []string{
+		//unknown field
+		"Lv2.Lv2Num2:>:100",
+		"Point.Lv1.Num1.Num2:>:100",
+		"priv:==:1",
+		//invalid selector
+		"Array[2]:==:0",
+		"Ports[a].Errors:==:0",
+		"Lv2[*]:==:0",
+		"Map[a].State:same:\"up\"",
+		//invalid operator for type
+		"Lv2.Lv2Num1:same:\"1\"",
+		"Lv2.Lv1.Num_uint1:contain:\"1\"",
+		"Lv2.Lv1.Str1:>:1",
+		"Lv2.Lv1.Bool1:>:true",
+		"Lv2.Lv1.IP1:>:1.1.1.1",
+		//unparsable value
+		"Lv2.Lv2Num1:>:1a",
+		"Lv2.Lv1.Num_uint1:>:-1",
+		"Lv2.Lv1.Float1:in:20 10",
+		"Lv2.Lv1.Duration1:>:5",
+		"Lv2.Lv1.Bool1:is:true maybe",
+		//unsupported type
+		"Chan:==:1",
+		"Lv2:==:1",
+	}, t)
+}
+
+// validateTest checks each rule of valid passes ParseRuleFor and CompileFor with type typ, and each rule of invalid fails
+func validateTest(typ reflect.Type, valid, invalid []string, t *testing.T) {
+	t.Helper()
+	check := func(rule string, expectErr bool) {
+		t.Helper()
+		err := NewDefaultCMPRule().ParseRuleFor(rule, typ)
+		t.Logf("rule %v, err: %v", rule, err)
+		if (err != nil) != expectErr {
+			t.Fatalf("rule %v unexpected err: %v", rule, err)
+		}
+		if _, err = CompileFor(rule, typ); (err != nil) != expectErr {
+			t.Fatalf("rule %v unexpected err: %v", rule, err)
+		}
+	}
+	for _, rule := range valid {
+		check(rule, false)
+	}
+	for _, rule := range invalid {
+		check(rule, true)
+	}
+}
+
+func TestCompileForWithFunc(t *testing.T) {
+	typ := reflect.TypeOf(testStructValidate{})
+	rule := "Lv2.Lv1.Bool1:==:yes"
+	if _, err := CompileFor(rule, typ); err == nil {
+		t.Fatalf("rule %v: expect validate error", rule)
+	}
+	newRule := func() *CMPRule {
+		r := NewDefaultCMPRule()
+		r.SetParseBoolFunc(func(boolstr string) (bool, error) {
+			return boolstr == "yes", nil
+		})
+		return r
+	}
+	r, err := CompileForWithFunc(rule, typ, newRule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.String() != rule {
+		t.Fatalf("expect compiled rule %v, got %v", rule, r.String())
+	}
+	if _, err := CompileForWithFunc("Lv2.Lv1.Bool1:>:yes", typ, newRule); err == nil {
+		t.Fatal("expect validate error for invalid operator")
+	}
+}
+
+func TestValidateRuleSet(t *testing.T) {
+	typ := reflect.TypeOf(testStruct{})
+	rs := NewRuleSet()
+	if err := rs.ParseRules("Num1:>:1", "Str1:same:\"a\" or not (Float1:<:1 and Bool1:==:true)"); err != nil {
+		t.Fatal(err)
+	}
+	if err := rs.Validate(typ); err != nil {
+		t.Fatal(err)
+	}
+	if err := rs.ParseRules("Num1:>:1 and (Str1:same:\"a\" or Float1:same:\"1\")"); err != nil {
+		t.Fatal(err)
+	}
+	err := rs.Validate(typ)
+	if err == nil {
+		t.Fatal("expect validate error")
+	}
+	t.Logf("expected err: %v", err)
+}
@@ -0,0 +1,358 @@
+// Copyright 2020 Hu Jun. All rights reserved.
+// This project is licensed under the terms of the MIT license.
+// license that can be found in the LICENSE file.
+
+package cmprule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// yamlLine is a non-empty line of a YAML document, without comment and indentation
+type yamlLine struct {
+	num    int
+	indent int
+	text   string
+}
+
+// yamlParser decodes a subset of YAML: block sequences and mappings, flow sequences and mappings,
+// plain, single-quoted and double-quoted scalars, and comments;
+// all scalars are decoded as string, except plain null and ~ as nil
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// decodeYAML decodes data into a tree of map[string]interface{}, []interface{} and string
+func decodeYAML(data []byte) (interface{}, error) {
+	p := &yamlParser{}
+	for i, l := range strings.Split(string(data), "\n") {
+		l = strings.TrimRight(stripYAMLComment(l), " \t\r")
+		text := strings.TrimLeft(l, " ")
+		if text == "" || (len(text) == len(l) && text == "---") {
+			continue
+		}
+		if text[0] == '\t' {
+			return nil, fmt.Errorf("line %d: tab is not allowed in indentation", i+1)
+		}
+		p.lines = append(p.lines, yamlLine{num: i + 1, indent: len(l) - len(text), text: text})
+	}
+	if len(p.lines) == 0 {
+		return nil, nil
+	}
+	v, err := p.parseNode()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, fmt.Errorf("line %d: unexpected indentation", p.lines[p.pos].num)
+	}
+	return v, nil
+}
+
+// stripYAMLComment removes the comment from line, a comment starts with '#' at the line start or after a space
+func stripYAMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// isYAMLSeqItem returns true if text is an item of a block sequence, like "- a"
+func isYAMLSeqItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// splitYAMLKey splits text like "key: value" into key and value, ok is false if text is not a mapping entry
+func splitYAMLKey(text string) (key, value string, ok bool) {
+	if text == "" || text[0] == '[' || text[0] == '{' {
+		return "", "", false
+	}
+	end := 0
+	if text[0] == '"' || text[0] == '\'' {
+		end = closingYAMLQuote(text)
+		if end < 0 {
+			return "", "", false
+		}
+		end++
+	}
+	for i := end; i < len(text); i++ {
+		if text[i] == ':' && (i+1 == len(text) || text[i+1] == ' ') {
+			k, err := parseYAMLFlow(strings.TrimSpace(text[:i]))
+			if err != nil {
+				return "", "", false
+			}
+			ks, isStr := k.(string)
+			return ks, strings.TrimSpace(text[i+1:]), isStr
+		}
+		if end > 0 {
+			//quoted key must be followed by ':'
+			return "", "", false
+		}
+	}
+	return "", "", false
+}
+
+// closingYAMLQuote returns the index of the quote closing the quoted scalar at the start of s, -1 if not found
+func closingYAMLQuote(s string) int {
+	quote := s[0]
+	for i := 1; i < len(s); i++ {
+		switch {
+		case quote == '"' && s[i] == '\\':
+			i++
+		case s[i] == quote:
+			if quote == '\'' && i+1 < len(s) && s[i+1] == '\'' {
+				i++
+				continue
+			}
+			return i
+		}
+	}
+	return -1
+}
+
+// parseNode parses the node starts at current line
+func (p *yamlParser) parseNode() (interface{}, error) {
+	l := p.lines[p.pos]
+	if isYAMLSeqItem(l.text) {
+		return p.parseSeq(l.indent)
+	}
+	if _, _, ok := splitYAMLKey(l.text); ok {
+		return p.parseMap(l.indent)
+	}
+	p.pos++
+	v, err := parseYAMLFlow(l.text)
+	if err != nil {
+		return nil, fmt.Errorf("line %d: %w", l.num, err)
+	}
+	return v, nil
+}
+
+// parseChild parses the node in following lines indented more than indent, returns nil if there is no such line;
+// a block sequence at the same indent is also a child, like "key:\n- a\n- b"
+func (p *yamlParser) parseChild(indent int, seqAllowed bool) (interface{}, error) {
+	if p.pos >= len(p.lines) {
+		return nil, nil
+	}
+	next := p.lines[p.pos]
+	if next.indent > indent || (seqAllowed && next.indent == indent && isYAMLSeqItem(next.text)) {
+		return p.parseNode()
+	}
+	return nil, nil
+}
+
+func (p *yamlParser) parseSeq(indent int) (interface{}, error) {
+	r := []interface{}{}
+	for p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		if l.indent != indent || !isYAMLSeqItem(l.text) {
+			break
+		}
+		rest := strings.TrimLeft(l.text[1:], " ")
+		if rest == "" {
+			p.pos++
+			v, err := p.parseChild(indent, false)
+			if err != nil {
+				return nil, err
+			}
+			r = append(r, v)
+			continue
+		}
+		//the content of item is parsed as a line indented at its column, like a mapping in "- key: value"
+		p.lines[p.pos] = yamlLine{num: l.num, indent: indent + len(l.text) - len(rest), text: rest}
+		v, err := p.parseNode()
+		if err != nil {
+			return nil, err
+		}
+		r = append(r, v)
+	}
+	if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+		return nil, fmt.Errorf("line %d: unexpected indentation", p.lines[p.pos].num)
+	}
+	return r, nil
+}
+
+func (p *yamlParser) parseMap(indent int) (interface{}, error) {
+	r := map[string]interface{}{}
+	for p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		if l.indent < indent {
+			break
+		}
+		if l.indent > indent {
+			return nil, fmt.Errorf("line %d: unexpected indentation", l.num)
+		}
+		key, value, ok := splitYAMLKey(l.text)
+		if !ok {
+			if isYAMLSeqItem(l.text) {
+				break
+			}
+			return nil, fmt.Errorf("line %d: expect key: value, got %v", l.num, l.text)
+		}
+		if _, exist := r[key]; exist {
+			return nil, fmt.Errorf("line %d: duplicate key %v", l.num, key)
+		}
+		p.pos++
+		var v interface{}
+		var err error
+		if value == "" {
+			v, err = p.parseChild(indent, true)
+		} else {
+			v, err = parseYAMLFlow(value)
+			if err != nil {
+				err = fmt.Errorf("line %d: %w", l.num, err)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		r[key] = v
+	}
+	return r, nil
+}
+
+// parseYAMLFlow parses s as a scalar or a flow sequence/mapping like "[1, 2]" or "{min: 1, max: 2}"
+func parseYAMLFlow(s string) (interface{}, error) {
+	f := &yamlFlow{s: s}
+	v, err := f.value(false)
+	if err != nil {
+		return nil, err
+	}
+	f.skipSpace()
+	if f.pos < len(f.s) {
+		return nil, fmt.Errorf("unexpected %q in %v", f.s[f.pos:], s)
+	}
+	return v, nil
+}
+
+// yamlFlow is a scanner of a flow value
+type yamlFlow struct {
+	s   string
+	pos int
+}
+
+func (f *yamlFlow) skipSpace() {
+	for f.pos < len(f.s) && f.s[f.pos] == ' ' {
+		f.pos++
+	}
+}
+
+// value parses the value at pos, inFlow is true if the value is within a flow sequence or mapping
+func (f *yamlFlow) value(inFlow bool) (interface{}, error) {
+	f.skipSpace()
+	if f.pos >= len(f.s) {
+		return nil, fmt.Errorf("missing value in %v", f.s)
+	}
+	switch f.s[f.pos] {
+	case '[':
+		f.pos++
+		r := []interface{}{}
+		for {
+			f.skipSpace()
+			if f.pos < len(f.s) && f.s[f.pos] == ']' && len(r) == 0 {
+				f.pos++
+				return r, nil
+			}
+			v, err := f.value(true)
+			if err != nil {
+				return nil, err
+			}
+			r = append(r, v)
+			if done, err := f.separator(']'); done || err != nil {
+				return r, err
+			}
+		}
+	case '{':
+		f.pos++
+		r := map[string]interface{}{}
+		for {
+			f.skipSpace()
+			if f.pos < len(f.s) && f.s[f.pos] == '}' && len(r) == 0 {
+				f.pos++
+				return r, nil
+			}
+			k, err := f.value(true)
+			if err != nil {
+				return nil, err
+			}
+			key, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid key in %v", f.s)
+			}
+			f.skipSpace()
+			if f.pos >= len(f.s) || f.s[f.pos] != ':' {
+				return nil, fmt.Errorf("missing : after key %v in %v", key, f.s)
+			}
+			f.pos++
+			v, err := f.value(true)
+			if err != nil {
+				return nil, err
+			}
+			r[key] = v
+			if done, err := f.separator('}'); done || err != nil {
+				return r, err
+			}
+		}
+	case '"', '\'':
+		end := closingYAMLQuote(f.s[f.pos:])
+		if end < 0 {
+			return nil, fmt.Errorf("unclosed quote in %v", f.s)
+		}
+		quoted := f.s[f.pos : f.pos+end+1]
+		f.pos += end + 1
+		if quoted[0] == '\'' {
+			return strings.ReplaceAll(quoted[1:len(quoted)-1], "''", "'"), nil
+		}
+		s, err := strconv.Unquote(quoted)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quoted string %v, %w", quoted, err)
+		}
+		return s, nil
+	}
+	start := f.pos
+	if inFlow {
+		for f.pos < len(f.s) && !strings.ContainsRune(",]}", rune(f.s[f.pos])) &&
+			!(f.s[f.pos] == ':' && (f.pos+1 == len(f.s) || strings.ContainsRune(" ,]}", rune(f.s[f.pos+1])))) {
+			f.pos++
+		}
+	} else {
+		f.pos = len(f.s)
+	}
+	plain := strings.TrimSpace(f.s[start:f.pos])
+	if plain == "null" || plain == "~" {
+		return nil, nil
+	}
+	return plain, nil
+}
+
+// separator reads a ',' or the closing bracket end, done is true if end is read
+func (f *yamlFlow) separator(end byte) (done bool, err error) {
+	f.skipSpace()
+	if f.pos >= len(f.s) {
+		return true, fmt.Errorf("missing %c in %v", end, f.s)
+	}
+	switch f.s[f.pos] {
+	case ',':
+		f.pos++
+		return false, nil
+	case end:
+		f.pos++
+		return true, nil
+	}
+	return true, fmt.Errorf("unexpected %q in %v", f.s[f.pos:], f.s)
+}
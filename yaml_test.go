@@ -0,0 +1,88 @@
+// cmprule_test
+package cmprule
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecodeYAML(t *testing.T) {
+	cases := []struct {
+		in       string
+		expected interface{}
+		err      bool
+	}{
+		{"", nil, false},
+		{"a", "a", false},
+		{"# comment\n- a\n- 'b ''c'''\n- \"d\\\"#e\" # comment\n", []interface{}{"a", "b 'c'", `d"#e`}, false},
+		{"a: 1\nb:\n  c: x y\n  d: [1, \"2, 3\", [], {}]\ne:\n- 1\n- ~\n", map[string]interface{}{
+			"a": "1",
+			"b": map[string]interface{}{"c": "x y", "d": []interface{}{"1", "2, 3", []interface{}{}, map[string]interface{}{}}},
+			"e": []interface{}{"1", nil},
+		}, false},
+		{"- field: Num1\n  op: \">\"\n  value: {min: 1, max: 1.1.1.1/24}\n-\n  field: x\n- - 1\n  - 2\n- time: 2020/03/31T15:00:00\n", []interface{}{
+			map[string]interface{}{"field": "Num1", "op": ">", "value": map[string]interface{}{"min": "1", "max": "1.1.1.1/24"}},
+			map[string]interface{}{"field": "x"},
+			[]interface{}{"1", "2"},
+			map[string]interface{}{"time": "2020/03/31T15:00:00"},
+		}, false},
+		{`"a: b": c`, map[string]interface{}{"a: b": "c"}, false},
+		{"a: 1\n  b: 2", nil, true},
+		{"a: 1\na: 2", nil, true},
+		{"a: [1, 2", nil, true},
+		{"a: {b 1}", nil, true},
+		{"a: \"b", nil, true},
+		{"- a\nb: 1", nil, true},
+		{"a: 1\n\t- b", nil, true},
+	}
+	for _, c := range cases {
+		v, err := decodeYAML([]byte(c.in))
+		t.Logf("%q: %#v, err: %v", c.in, v, err)
+		if (err != nil) != c.err {
+			t.Fatalf("%q: unexpected err: %v", c.in, err)
+		}
+		if err == nil && !reflect.DeepEqual(v, c.expected) {
+			t.Fatalf("%q: expect %#v, got %#v", c.in, c.expected, v)
+		}
+	}
+}
+
+func TestParseRuleDefsYAML(t *testing.T) {
+	defs, err := ParseRuleDefsYAML([]byte(`
+# stats checks
+- field: Num1
+  op: ">"
+  value: 100
+- field: Str1
+  op: contain
+  value: ['"quoted"', "#hash"]
+- field: Num1
+  op: in
+  value:
+    min: 100
+    max: 300
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"Num1 : > : 100",
+		`Str1 : contain : "\"quoted\"" "#hash"`,
+		"Num1 : in : 100 300",
+	}
+	rs := NewRuleSet()
+	if err := rs.AddRuleDefs(defs...); err != nil {
+		t.Fatal(err)
+	}
+	results := rs.Evaluate(testStruct{Num1: 200, Str1: "#hash"})
+	for i, r := range results {
+		if r.Rule != expected[i] || !r.Passed() {
+			t.Fatalf("expect %v passed, got %+v", expected[i], r)
+		}
+	}
+	for _, bad := range []string{"field: Num1", "- Num1", "- field: [1]", "- field: Num1\n  opp: >"} {
+		if _, err := ParseRuleDefsYAML([]byte(bad)); err == nil {
+			t.Fatalf("expect error for %v", bad)
+		}
+	}
+}